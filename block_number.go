@@ -0,0 +1,148 @@
+package ginfura
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// BlockNumber is a block number or, when negative, one of the block tags.
+type BlockNumber int64
+
+// block tags
+const (
+	EarliestBlockNumber  BlockNumber = -5
+	SafeBlockNumber      BlockNumber = -4
+	FinalizedBlockNumber BlockNumber = -3
+	LatestBlockNumber    BlockNumber = -2
+	PendingBlockNumber   BlockNumber = -1
+)
+
+var blockTags = map[BlockNumber]string{
+	EarliestBlockNumber:  "earliest",
+	SafeBlockNumber:      "safe",
+	FinalizedBlockNumber: "finalized",
+	LatestBlockNumber:    "latest",
+	PendingBlockNumber:   "pending",
+}
+
+// ParseBlockNumber parses a decimal or 0x-prefixed hex block number, or one
+// of the `latest`, `pending`, `earliest`, `safe` and `finalized` tags.
+func ParseBlockNumber(s string) (BlockNumber, error) {
+	for n, tag := range blockTags {
+		if s == tag {
+			return n, nil
+		}
+	}
+
+	base := 10
+	if len(s) > 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		s, base = s[2:], 16
+	}
+	n, err := strconv.ParseUint(s, base, 63)
+	if err != nil {
+		return 0, errInvalidBlockNumber
+	}
+	return BlockNumber(n), nil
+}
+
+func (n BlockNumber) String() string {
+	if tag, ok := blockTags[n]; ok {
+		return tag
+	}
+	if n < 0 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return Quantity(n).String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (n BlockNumber) MarshalText() ([]byte, error) {
+	if _, ok := blockTags[n]; n < 0 && !ok {
+		return nil, errInvalidBlockNumber
+	}
+	return []byte(n.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (n *BlockNumber) UnmarshalText(input []byte) error {
+	v, err := ParseBlockNumber(string(input))
+	if err != nil {
+		return err
+	}
+	*n = v
+	return nil
+}
+
+// BlockNumberOrHash selects the block whose state is read, either by number
+// or tag, or by hash as described in EIP-1898. The zero value selects the
+// latest block.
+type BlockNumberOrHash struct {
+	number           *BlockNumber
+	hash             *Hash
+	requireCanonical bool
+}
+
+// BlockNumberOrHashWithNumber selects the block with the given number or tag.
+func BlockNumberOrHashWithNumber(n BlockNumber) BlockNumberOrHash {
+	return BlockNumberOrHash{number: &n}
+}
+
+// BlockNumberOrHashWithHash selects the block with the given hash. If
+// requireCanonical is set the node fails the call when the block is not part
+// of the canonical chain.
+func BlockNumberOrHashWithHash(h Hash, requireCanonical bool) BlockNumberOrHash {
+	return BlockNumberOrHash{hash: &h, requireCanonical: requireCanonical}
+}
+
+// Number returns the selected block number, if selected by number.
+func (b BlockNumberOrHash) Number() (BlockNumber, bool) {
+	if b.hash != nil {
+		return 0, false
+	}
+	if b.number == nil {
+		return LatestBlockNumber, true
+	}
+	return *b.number, true
+}
+
+// Hash returns the selected block hash, if selected by hash.
+func (b BlockNumberOrHash) Hash() (Hash, bool) {
+	if b.hash == nil {
+		return Hash{}, false
+	}
+	return *b.hash, true
+}
+
+type blockHashParam struct {
+	BlockHash        Hash `json:"blockHash"`
+	RequireCanonical bool `json:"requireCanonical"`
+}
+
+// MarshalJSON encodes b as a block number or tag, or as an EIP-1898 object.
+func (b BlockNumberOrHash) MarshalJSON() ([]byte, error) {
+	if h, ok := b.Hash(); ok {
+		return json.Marshal(blockHashParam{BlockHash: h, RequireCanonical: b.requireCanonical})
+	}
+	n, _ := b.Number()
+	return json.Marshal(n)
+}
+
+// UnmarshalJSON decodes a block number or tag, or an EIP-1898 object.
+func (b *BlockNumberOrHash) UnmarshalJSON(input []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(input), []byte("{")) {
+		var dec blockHashParam
+		if err := json.Unmarshal(input, &dec); err != nil {
+			return err
+		}
+		*b = BlockNumberOrHashWithHash(dec.BlockHash, dec.RequireCanonical)
+		return nil
+	}
+
+	var n BlockNumber
+	if err := json.Unmarshal(input, &n); err != nil {
+		return err
+	}
+	*b = BlockNumberOrHashWithNumber(n)
+	return nil
+}
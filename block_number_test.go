@@ -0,0 +1,64 @@
+package ginfura
+
+import "testing"
+
+func TestParseBlockNumber(t *testing.T) {
+	tests := []struct {
+		input string
+		want  BlockNumber
+		err   bool
+	}{
+		{input: "earliest", want: EarliestBlockNumber},
+		{input: "safe", want: SafeBlockNumber},
+		{input: "finalized", want: FinalizedBlockNumber},
+		{input: "latest", want: LatestBlockNumber},
+		{input: "pending", want: PendingBlockNumber},
+		{input: "0x0", want: 0},
+		{input: "0x10", want: 16},
+		{input: "0X1b4", want: 436},
+		{input: "0x7fffffffffffffff", want: 1<<63 - 1},
+		{input: "0", want: 0},
+		{input: "10", want: 10},
+		{input: "010", want: 10},
+		{input: "19000000", want: 19000000},
+		{input: "", err: true},
+		{input: "0x", err: true},
+		{input: "0xg", err: true},
+		{input: "0x8000000000000000", err: true},
+		{input: "-1", err: true},
+		{input: "0b101", err: true},
+		{input: "0o17", err: true},
+		{input: "1_000", err: true},
+		{input: "0x1_0", err: true},
+		{input: "Latest", err: true},
+	}
+
+	for _, test := range tests {
+		n, err := ParseBlockNumber(test.input)
+		if test.err {
+			if err == nil {
+				t.Errorf("ParseBlockNumber(%q) = %d, want an error", test.input, n)
+			}
+			continue
+		}
+		if err != nil || n != test.want {
+			t.Errorf("ParseBlockNumber(%q) = %d, %v, want %d", test.input, n, err, test.want)
+		}
+	}
+}
+
+func TestBlockNumberText(t *testing.T) {
+	for _, n := range []BlockNumber{EarliestBlockNumber, LatestBlockNumber, 0, 46147} {
+		text, err := n.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded BlockNumber
+		if err := decoded.UnmarshalText(text); err != nil || decoded != n {
+			t.Errorf("%s decoded to %d, %v", text, decoded, err)
+		}
+	}
+	if _, err := BlockNumber(-6).MarshalText(); err == nil {
+		t.Error("expected an error for an unknown tag")
+	}
+}
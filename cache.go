@@ -0,0 +1,152 @@
+package ginfura
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+// Cache stores the raw results of finality-safe JSON-RPC responses. It can be
+// backed by an external store; implementations must be safe for concurrent
+// use.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// cacheableMethods lists the methods whose results never change once
+// available, given that a transaction or receipt is only cached once its
+// block is finalized.
+var cacheableMethods = map[string]bool{
+	"eth_getBlockByHash":                    true,
+	"eth_getBlockTransactionCountByHash":    true,
+	"eth_getTransactionByBlockHashAndIndex": true,
+	"eth_getTransactionByHash":              true,
+	"eth_getTransactionReceipt":             true,
+	"eth_getUncleByBlockHashAndIndex":       true,
+	"eth_getUncleCountByBlockHash":          true,
+}
+
+// isFinal reports whether the result of method can be cached. Missing
+// results are not, nor are transactions and receipts whose block is not
+// finalized yet, since a reorg could still drop them or move them to another
+// block.
+func (e *Ginfura) isFinal(ctx context.Context, method string, result json.RawMessage) bool {
+	if isNull(result) {
+		return false
+	}
+
+	switch method {
+	case "eth_getTransactionByHash", "eth_getTransactionReceipt":
+		var mined struct {
+			BlockNumber *Quantity `json:"blockNumber"`
+		}
+		if json.Unmarshal(result, &mined) != nil || mined.BlockNumber == nil {
+			return false
+		}
+		finalized, err := e.finalizedBlockNumber(ctx, mined.BlockNumber.Uint64())
+		return err == nil && mined.BlockNumber.Uint64() <= finalized
+	}
+	return true
+}
+
+// finalizedBlockNumber returns the number of the finalized block. The node is
+// only queried if the last finalized block seen is below number.
+func (e *Ginfura) finalizedBlockNumber(ctx context.Context, number uint64) (uint64, error) {
+	finalized := atomic.LoadUint64(&e.finalized)
+	if number <= finalized {
+		return finalized, nil
+	}
+
+	var head struct {
+		Number Quantity `json:"number"`
+	}
+	if err := e.CallContext(ctx, &head, "eth_getBlockByNumber", FinalizedBlockNumber, false); err != nil {
+		return 0, err
+	}
+	for {
+		finalized = atomic.LoadUint64(&e.finalized)
+		if head.Number.Uint64() <= finalized || atomic.CompareAndSwapUint64(&e.finalized, finalized, head.Number.Uint64()) {
+			break
+		}
+	}
+	return atomic.LoadUint64(&e.finalized), nil
+}
+
+// LRUCache is an in-memory Cache evicting the least recently used entries
+// once its entry or size limit is reached.
+type LRUCache struct {
+	maxEntries int
+	maxBytes   int
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewLRUCache returns an LRU cache holding at most maxEntries entries and
+// maxBytes bytes of values. A zero limit is unlimited.
+func NewLRUCache(maxEntries, maxBytes int) *LRUCache {
+	return &LRUCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored under key.
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// Set stores value under key, evicting old entries as needed.
+func (c *LRUCache) Set(key string, value []byte) {
+	if c.maxBytes > 0 && len(value) > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		c.size += len(value) - len(entry.value)
+		entry.value = value
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+		c.size += len(value)
+	}
+
+	for c.order.Len() > 0 && (c.maxEntries > 0 && c.order.Len() > c.maxEntries || c.maxBytes > 0 && c.size > c.maxBytes) {
+		elem := c.order.Back()
+		entry := elem.Value.(*lruEntry)
+		c.order.Remove(elem)
+		delete(c.entries, entry.key)
+		c.size -= len(entry.value)
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
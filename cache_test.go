@@ -0,0 +1,183 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestLRUCacheMaxEntries(t *testing.T) {
+	c := NewLRUCache(2, 0)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a was evicted")
+	}
+
+	// b is now the least recently used entry.
+	c.Set("c", []byte("3"))
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%s was evicted", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Fatalf("len = %d, want 2", c.Len())
+	}
+}
+
+func TestLRUCacheMaxBytes(t *testing.T) {
+	c := NewLRUCache(0, 10)
+	c.Set("a", []byte("1234"))
+	c.Set("b", []byte("1234"))
+	c.Set("c", []byte("1234"))
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a was not evicted")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("len = %d, want 2", c.Len())
+	}
+
+	// replacing a value accounts for the size difference.
+	c.Set("b", []byte("12345678"))
+	if _, ok := c.Get("c"); ok {
+		t.Fatal("c was not evicted")
+	}
+	if value, ok := c.Get("b"); !ok || string(value) != "12345678" {
+		t.Fatalf("b = %q, %v", value, ok)
+	}
+
+	// values larger than the cache are not stored.
+	c.Set("d", []byte("12345678901"))
+	if _, ok := c.Get("d"); ok {
+		t.Fatal("oversized value was stored")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Fatal("b was evicted by an oversized value")
+	}
+}
+
+// cacheServer serves transactions and receipts by hash, counting the calls
+// per method.
+type cacheServer struct {
+	finalized uint64
+	txs       map[Hash]interface{}
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *cacheServer) handle(method string, params []json.RawMessage) (interface{}, *RPCError) {
+	s.mu.Lock()
+	s.calls[method]++
+	s.mu.Unlock()
+
+	switch method {
+	case "eth_getBlockByNumber":
+		return map[string]interface{}{"number": Quantity(s.finalized)}, nil
+	case "eth_getBlockByHash":
+		return map[string]interface{}{"number": Quantity(1)}, nil
+	case "eth_getTransactionByHash", "eth_getTransactionReceipt":
+		var hash Hash
+		json.Unmarshal(params[0], &hash)
+		return s.txs[hash], nil
+	}
+	return nil, &RPCError{Code: ErrCodeMethodNotFound, Message: "method not found"}
+}
+
+func (s *cacheServer) count(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func minedTx(hash Hash, block uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"hash":        hash,
+		"blockHash":   blockHash(block),
+		"blockNumber": Quantity(block),
+	}
+}
+
+func TestCacheFinality(t *testing.T) {
+	final, unfinal, pending, missing := Hash{1}, Hash{2}, Hash{3}, Hash{4}
+	s := &cacheServer{
+		finalized: 100,
+		txs: map[Hash]interface{}{
+			final:   minedTx(final, 90),
+			unfinal: minedTx(unfinal, 101),
+			pending: map[string]interface{}{"hash": pending, "blockHash": nil, "blockNumber": nil},
+		},
+		calls: make(map[string]int),
+	}
+	cache := NewLRUCache(0, 0)
+	g := NewGinfura("mainnet", "", WithURL(newRPCServer(t, s.handle).URL), WithCache(cache))
+
+	for _, hash := range []Hash{final, unfinal, pending, missing} {
+		for i := 0; i < 2; i++ {
+			var result json.RawMessage
+			if err := g.CallContext(context.Background(), &result, "eth_getTransactionByHash", hash); err != nil {
+				t.Fatal(err)
+			}
+			var receipt json.RawMessage
+			if err := g.CallContext(context.Background(), &receipt, "eth_getTransactionReceipt", hash); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// only the transaction and receipt of the finalized block are served from
+	// the cache the second time.
+	if n := s.count("eth_getTransactionByHash"); n != 7 {
+		t.Fatalf("eth_getTransactionByHash calls = %d, want 7", n)
+	}
+	if n := s.count("eth_getTransactionReceipt"); n != 7 {
+		t.Fatalf("eth_getTransactionReceipt calls = %d, want 7", n)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("cached entries = %d, want 2", cache.Len())
+	}
+
+	// the finalized block number is queried once for the finalized block,
+	// then for every response of the block above it.
+	if n := s.count("eth_getBlockByNumber"); n != 5 {
+		t.Fatalf("eth_getBlockByNumber calls = %d, want 5", n)
+	}
+}
+
+func TestCacheBlockByHash(t *testing.T) {
+	s := &cacheServer{calls: make(map[string]int)}
+	g := NewGinfura("mainnet", "", WithURL(newRPCServer(t, s.handle).URL), WithCache(NewLRUCache(10, 0)))
+
+	for i := 0; i < 3; i++ {
+		var result json.RawMessage
+		if err := g.CallContext(context.Background(), &result, "eth_getBlockByHash", blockHash(1), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.count("eth_getBlockByHash"); n != 1 {
+		t.Fatalf("eth_getBlockByHash calls = %d, want 1", n)
+	}
+}
+
+func TestCacheNotFound(t *testing.T) {
+	s := &cacheServer{calls: make(map[string]int)}
+	cache := NewLRUCache(0, 0)
+	g := NewGinfura("mainnet", "", WithURL(newRPCServer(t, s.handle).URL), WithCache(cache))
+
+	for i := 0; i < 2; i++ {
+		if _, err := g.GetTransactionReceipt(context.Background(), Hash{9}); err != ErrNotFound {
+			t.Fatalf("err = %v, want %v", err, ErrNotFound)
+		}
+	}
+	if n := s.count("eth_getTransactionReceipt"); n != 2 {
+		t.Fatalf("eth_getTransactionReceipt calls = %d, want 2", n)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("cached entries = %d, want 0", cache.Len())
+	}
+}
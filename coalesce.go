@@ -0,0 +1,111 @@
+package ginfura
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// headDependentMethods lists the methods whose results only change with the
+// chain head, so that a briefly stale result is acceptable.
+var headDependentMethods = map[string]bool{
+	"eth_blockNumber":          true,
+	"eth_gasPrice":             true,
+	"eth_maxPriorityFeePerGas": true,
+	"eth_blobBaseFee":          true,
+	"eth_feeHistory":           true,
+}
+
+// coalescedCall is an in-flight or recently completed call shared by
+// identical requests.
+type coalescedCall struct {
+	done    chan struct{}
+	resp    *jsonrpcMessage
+	err     error
+	expires time.Time
+}
+
+// coalescer collapses identical concurrent calls into one upstream request.
+type coalescer struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+func newCoalescer(ttl time.Duration) *coalescer {
+	return &coalescer{
+		ttl:   ttl,
+		calls: make(map[string]*coalescedCall),
+	}
+}
+
+// do returns the response of send, sharing it with every identical call made
+// while it is in flight. A nil coalescer always calls send.
+func (c *coalescer) do(ctx context.Context, key string, msg *jsonrpcMessage, send func() (*jsonrpcMessage, error)) (*jsonrpcMessage, error) {
+	if c == nil || !isIdempotent(msg) {
+		return send()
+	}
+
+	c.mu.Lock()
+	if call, ok := c.calls[key]; ok {
+		select {
+		case <-call.done:
+			if time.Now().Before(call.expires) {
+				c.mu.Unlock()
+				return call.resp, call.err
+			}
+		default:
+			c.mu.Unlock()
+			return c.wait(ctx, call, send)
+		}
+	}
+
+	call := &coalescedCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	call.resp, call.err = send()
+
+	c.mu.Lock()
+	if c.ttl > 0 && headDependentMethods[msg.Method] && call.err == nil && call.resp.Error == nil {
+		call.expires = time.Now().Add(c.ttl)
+		time.AfterFunc(c.ttl, func() { c.forget(key, call) })
+	} else if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.resp, call.err
+}
+
+// forget removes call unless it was already replaced by a newer call.
+func (c *coalescer) forget(key string, call *coalescedCall) {
+	c.mu.Lock()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+	c.mu.Unlock()
+}
+
+// wait waits for an in-flight call. If the call was aborted by the context
+// of the caller that started it, the request is sent again on behalf of this
+// caller.
+func (c *coalescer) wait(ctx context.Context, call *coalescedCall, send func() (*jsonrpcMessage, error)) (*jsonrpcMessage, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+	}
+
+	if isContextError(call.err) && ctx.Err() == nil {
+		return send()
+	}
+	return call.resp, call.err
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,130 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var latest = BlockNumberOrHashWithNumber(LatestBlockNumber)
+
+func TestCoalescing(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	srv := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, *RPCError) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "0x1", nil
+	})
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithCoalescing(0))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.GetBalance(context.Background(), Address{0x01}, latest)
+			errs <- err
+		}()
+	}
+	waitFor(t, "the first call", func() bool { return atomic.LoadInt32(&calls) == 1 })
+	// give the other calls time to join the in-flight one.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("calls = %d, want 1", n)
+	}
+
+	// completed calls are not shared without a ttl.
+	if _, err := g.GetBalance(context.Background(), Address{0x01}, latest); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("calls = %d, want 2", n)
+	}
+}
+
+func TestCoalescingDistinctParams(t *testing.T) {
+	var calls int32
+	srv := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, *RPCError) {
+		atomic.AddInt32(&calls, 1)
+		return "0x1", nil
+	})
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithCoalescing(time.Minute))
+
+	for _, address := range []Address{{0x01}, {0x02}} {
+		if _, err := g.GetBalance(context.Background(), address, latest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("calls = %d, want 2", n)
+	}
+}
+
+func TestCoalescingTTL(t *testing.T) {
+	var calls int32
+	srv := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, *RPCError) {
+		atomic.AddInt32(&calls, 1)
+		return "0x10", nil
+	})
+	ttl := 50 * time.Millisecond
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithCoalescing(ttl))
+
+	for i := 0; i < 3; i++ {
+		if _, err := g.GetBlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("calls within the ttl = %d, want 1", n)
+	}
+
+	// expired results are dropped from the map and fetched again.
+	waitFor(t, "the coalescer to drain", func() bool {
+		g.coalescer.mu.Lock()
+		defer g.coalescer.mu.Unlock()
+		return len(g.coalescer.calls) == 0
+	})
+	if _, err := g.GetBlockNumber(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("calls after the ttl = %d, want 2", n)
+	}
+}
+
+func TestCoalescingErrorsNotShared(t *testing.T) {
+	var calls int32
+	srv := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, *RPCError) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil, &RPCError{Code: ErrCodeInternalError, Message: "internal error"}
+		}
+		return "0x10", nil
+	})
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithCoalescing(time.Minute), WithRetryPolicy(RetryPolicy{}))
+
+	if _, err := g.GetBlockNumber(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n, err := g.GetBlockNumber(context.Background()); err != nil || n != 16 {
+		t.Fatalf("GetBlockNumber = %d, %v", n, err)
+	}
+	g.coalescer.mu.Lock()
+	defer g.coalescer.mu.Unlock()
+	if len(g.coalescer.calls) != 1 {
+		t.Fatalf("coalesced calls = %d, want 1", len(g.coalescer.calls))
+	}
+}
@@ -0,0 +1,190 @@
+package ginfura
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Endpoint is a JSON-RPC provider of a failover client.
+type Endpoint struct {
+	URL   string
+	WSURL string
+	// Header is sent in addition to the client headers, e.g. for the
+	// authentication of a non-Infura provider.
+	Header http.Header
+}
+
+// FailoverConfig configures the health checks of a failover client.
+type FailoverConfig struct {
+	// HealthCheckInterval is the delay between two health checks. It
+	// defaults to 15 seconds.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds each eth_blockNumber probe. It defaults to 5
+	// seconds.
+	HealthCheckTimeout time.Duration
+	// MaxBlockLag is the number of blocks an endpoint may lag behind the
+	// highest head seen before it is considered unhealthy. It defaults to 5.
+	MaxBlockLag uint64
+}
+
+type endpointState struct {
+	Endpoint
+	healthy bool
+	head    uint64
+}
+
+// failover tracks the health of an ordered list of endpoints.
+type failover struct {
+	cfg FailoverConfig
+
+	mu        sync.RWMutex
+	endpoints []*endpointState
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFailoverGinfura returns a client that sends requests to the first
+// healthy endpoint in the given order. Endpoints are health-checked in the
+// background with eth_blockNumber; failing or lagging ones are skipped until
+// they recover, so traffic fails back to the primary endpoint. Call Close to
+// stop the health checks.
+//
+// The client has no network name, so VerifyChainID checks that all endpoints
+// serve the same chain, or the one set with WithChainID.
+func NewFailoverGinfura(endpoints []Endpoint, cfg FailoverConfig, opts ...Option) (*Ginfura, error) {
+	if len(endpoints) == 0 {
+		return nil, errNoEndpoints
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = 15 * time.Second
+	}
+	if cfg.HealthCheckTimeout <= 0 {
+		cfg.HealthCheckTimeout = 5 * time.Second
+	}
+	if cfg.MaxBlockLag == 0 {
+		cfg.MaxBlockLag = 5
+	}
+
+	f := &failover{
+		cfg:  cfg,
+		stop: make(chan struct{}),
+	}
+	for _, endpoint := range endpoints {
+		f.endpoints = append(f.endpoints, &endpointState{Endpoint: endpoint, healthy: true})
+	}
+
+	g := newGinfura("", endpoints[0].URL, endpoints[0].WSURL, opts...)
+	g.failover = f
+	go f.run(g)
+
+	return g, nil
+}
+
+// all returns the endpoints in priority order.
+func (f *failover) all() []Endpoint {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	endpoints := make([]Endpoint, len(f.endpoints))
+	for i, state := range f.endpoints {
+		endpoints[i] = state.Endpoint
+	}
+	return endpoints
+}
+
+// candidates returns the healthy endpoints in priority order, followed by
+// the unhealthy ones as a last resort.
+func (f *failover) candidates() []Endpoint {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	healthy := make([]Endpoint, 0, len(f.endpoints))
+	var unhealthy []Endpoint
+	for _, state := range f.endpoints {
+		if state.healthy {
+			healthy = append(healthy, state.Endpoint)
+		} else {
+			unhealthy = append(unhealthy, state.Endpoint)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// markFailed marks the endpoint with the given URL unhealthy until the next
+// successful health check.
+func (f *failover) markFailed(url string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, state := range f.endpoints {
+		if state.URL == url {
+			state.healthy = false
+		}
+	}
+}
+
+// run health-checks the endpoints until the failover is closed.
+func (f *failover) run(g *Ginfura) {
+	ticker := time.NewTicker(f.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	f.check(g)
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			f.check(g)
+		}
+	}
+}
+
+// check probes every endpoint concurrently and updates their health.
+func (f *failover) check(g *Ginfura) {
+	endpoints := f.all()
+	heads := make([]uint64, len(endpoints))
+	errs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint Endpoint) {
+			defer wg.Done()
+			heads[i], errs[i] = f.probe(g, endpoint)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	var best uint64
+	for i, head := range heads {
+		if errs[i] == nil && head > best {
+			best = head
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, state := range f.endpoints {
+		state.head = heads[i]
+		state.healthy = errs[i] == nil && heads[i]+f.cfg.MaxBlockLag >= best
+	}
+}
+
+// probe returns the head block number reported by endpoint.
+func (f *failover) probe(g *Ginfura, endpoint Endpoint) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.cfg.HealthCheckTimeout)
+	defer cancel()
+
+	var result string
+	if err := g.callEndpoint(ctx, endpoint, &result, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(result, 0, 64)
+}
+
+func (f *failover) close() {
+	f.stopOnce.Do(func() { close(f.stop) })
+}
@@ -0,0 +1,235 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testEndpoint is a JSON-RPC endpoint that can be made to fail or lag.
+type testEndpoint struct {
+	url     string
+	head    int64 // head block number reported by eth_blockNumber
+	down    int32 // non-zero makes every request fail with HTTP 503
+	chainID int32 // served by eth_chainId
+	calls   int32 // eth_chainId calls served
+}
+
+func newTestEndpoint(t *testing.T, head int64) *testEndpoint {
+	t.Helper()
+
+	e := &testEndpoint{head: head, chainID: 1}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&e.down) != 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var req jsonrpcMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "eth_blockNumber":
+			result = Quantity(atomic.LoadInt64(&e.head)).String()
+		case "eth_chainId":
+			atomic.AddInt32(&e.calls, 1)
+			result = Quantity(atomic.LoadInt32(&e.chainID)).String()
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(srv.Close)
+	e.url = srv.URL
+	return e
+}
+
+func (e *testEndpoint) setDown(down bool) {
+	var v int32
+	if down {
+		v = 1
+	}
+	atomic.StoreInt32(&e.down, v)
+}
+
+func (e *testEndpoint) served() int32 {
+	return atomic.SwapInt32(&e.calls, 0)
+}
+
+func newTestFailover(t *testing.T, cfg FailoverConfig, endpoints ...*testEndpoint) *Ginfura {
+	t.Helper()
+
+	list := make([]Endpoint, len(endpoints))
+	for i, endpoint := range endpoints {
+		list[i] = Endpoint{URL: endpoint.url}
+	}
+	g, err := NewFailoverGinfura(list, cfg, WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+
+	// let the initial health check complete so that it does not race with
+	// the endpoint failures injected by the tests.
+	waitFor(t, "the initial health check", func() bool {
+		g.failover.mu.RLock()
+		defer g.failover.mu.RUnlock()
+		for _, state := range g.failover.endpoints {
+			if state.head == 0 {
+				return false
+			}
+		}
+		return true
+	})
+	return g
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFailoverOrder(t *testing.T) {
+	primary, secondary := newTestEndpoint(t, 100), newTestEndpoint(t, 100)
+	g := newTestFailover(t, FailoverConfig{HealthCheckInterval: time.Hour}, primary, secondary)
+
+	if _, err := g.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p, s := primary.served(), secondary.served(); p != 1 || s != 0 {
+		t.Fatalf("served by primary %d, secondary %d; want 1, 0", p, s)
+	}
+
+	// a failing primary is skipped until it recovers.
+	primary.setDown(true)
+	for i := 0; i < 3; i++ {
+		if _, err := g.ChainID(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, s := primary.served(), secondary.served(); p != 0 || s != 3 {
+		t.Fatalf("served by primary %d, secondary %d; want 0, 3", p, s)
+	}
+	if candidates := g.failover.candidates(); candidates[0].URL != secondary.url {
+		t.Fatalf("first candidate = %s, want the secondary endpoint", candidates[0].URL)
+	}
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	primary, secondary := newTestEndpoint(t, 100), newTestEndpoint(t, 100)
+	g := newTestFailover(t, FailoverConfig{HealthCheckInterval: time.Hour}, primary, secondary)
+	primary.setDown(true)
+	secondary.setDown(true)
+
+	_, err := g.ChainID(context.Background())
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want HTTP 503", err)
+	}
+
+	// unhealthy endpoints are still tried as a last resort.
+	secondary.setDown(false)
+	if _, err := g.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailoverRecovery(t *testing.T) {
+	primary, secondary := newTestEndpoint(t, 100), newTestEndpoint(t, 100)
+	g := newTestFailover(t, FailoverConfig{HealthCheckInterval: 20 * time.Millisecond}, primary, secondary)
+
+	primary.setDown(true)
+	waitFor(t, "the primary endpoint to be marked unhealthy", func() bool {
+		return g.failover.candidates()[0].URL == secondary.url
+	})
+	if _, err := g.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p, s := primary.served(), secondary.served(); p != 0 || s != 1 {
+		t.Fatalf("served by primary %d, secondary %d; want 0, 1", p, s)
+	}
+
+	// traffic fails back to the primary once a health check succeeds.
+	primary.setDown(false)
+	waitFor(t, "the primary endpoint to recover", func() bool {
+		return g.failover.candidates()[0].URL == primary.url
+	})
+	if _, err := g.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p, s := primary.served(), secondary.served(); p != 1 || s != 0 {
+		t.Fatalf("served by primary %d, secondary %d; want 1, 0", p, s)
+	}
+}
+
+func TestFailoverBlockLag(t *testing.T) {
+	primary, secondary := newTestEndpoint(t, 100), newTestEndpoint(t, 110)
+	g := newTestFailover(t, FailoverConfig{HealthCheckInterval: 20 * time.Millisecond, MaxBlockLag: 5}, primary, secondary)
+
+	waitFor(t, "the lagging primary endpoint to be skipped", func() bool {
+		return g.failover.candidates()[0].URL == secondary.url
+	})
+
+	atomic.StoreInt64(&primary.head, 108)
+	waitFor(t, "the primary endpoint to catch up", func() bool {
+		return g.failover.candidates()[0].URL == primary.url
+	})
+}
+
+func TestFailoverVerifyChainID(t *testing.T) {
+	primary, secondary := newTestEndpoint(t, 100), newTestEndpoint(t, 100)
+	g := newTestFailover(t, FailoverConfig{HealthCheckInterval: time.Hour}, primary, secondary)
+	if err := g.VerifyChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// unreachable endpoints are skipped.
+	primary.setDown(true)
+	if err := g.VerifyChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	primary.setDown(false)
+
+	atomic.StoreInt32(&secondary.chainID, 137)
+	err := g.VerifyChainID(context.Background())
+	mismatch, ok := err.(*ChainMismatchError)
+	if !ok || mismatch.Expected != 1 || mismatch.Actual != 137 {
+		t.Fatalf("err = %v, want a chain id mismatch", err)
+	}
+}
+
+func TestFailoverVerifyConfiguredChainID(t *testing.T) {
+	primary, secondary := newTestEndpoint(t, 100), newTestEndpoint(t, 100)
+	list := []Endpoint{{URL: primary.url}, {URL: secondary.url}}
+	g, err := NewFailoverGinfura(list, FailoverConfig{HealthCheckInterval: time.Hour}, WithChainID(137))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	err = g.VerifyChainID(context.Background())
+	if mismatch, ok := err.(*ChainMismatchError); !ok || mismatch.Expected != 137 || mismatch.Actual != 1 {
+		t.Fatalf("err = %v, want a chain id mismatch", err)
+	}
+
+	primary.setDown(true)
+	secondary.setDown(true)
+	var httpErr *HTTPError
+	if err := g.VerifyChainID(context.Background()); !errors.As(err, &httpErr) {
+		t.Fatalf("err = %v, want the error of the unreachable endpoints", err)
+	}
+}
@@ -0,0 +1,429 @@
+package ginfura
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// defaultPollInterval is how often polling subscriptions query their
+	// filter.
+	defaultPollInterval = 4 * time.Second
+	// maxPollFailures is the number of consecutive failed polls after which
+	// a polling subscription ends.
+	maxPollFailures = 5
+)
+
+// NewFilter installs a filter for new logs matching q and returns its id.
+func (e *Ginfura) NewFilter(ctx context.Context, q FilterQuery) (string, error) {
+	var result string
+	if err := e.CallContext(ctx, &result, "eth_newFilter", q); err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+// NewBlockFilter installs a filter for newly imported blocks and returns its
+// id.
+func (e *Ginfura) NewBlockFilter(ctx context.Context) (string, error) {
+	var result string
+	if err := e.CallContext(ctx, &result, "eth_newBlockFilter"); err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+// NewPendingTransactionFilter installs a filter for new pending transactions
+// and returns its id.
+func (e *Ginfura) NewPendingTransactionFilter(ctx context.Context) (string, error) {
+	var result string
+	if err := e.CallContext(ctx, &result, "eth_newPendingTransactionFilter"); err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+// GetFilterChanges returns the logs matched by the log filter id since it was
+// last polled.
+func (e *Ginfura) GetFilterChanges(ctx context.Context, id string) ([]Log, error) {
+	var result []Log
+	if err := e.CallContext(ctx, &result, "eth_getFilterChanges", id); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetFilterHashChanges returns the block or transaction hashes reported by the
+// block or pending transaction filter id since it was last polled.
+func (e *Ginfura) GetFilterHashChanges(ctx context.Context, id string) ([]Hash, error) {
+	var result []Hash
+	if err := e.CallContext(ctx, &result, "eth_getFilterChanges", id); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetFilterLogs returns all logs matching the log filter id.
+func (e *Ginfura) GetFilterLogs(ctx context.Context, id string) ([]Log, error) {
+	var result []Log
+	if err := e.CallContext(ctx, &result, "eth_getFilterLogs", id); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UninstallFilter removes the filter id and reports whether it existed.
+func (e *Ginfura) UninstallFilter(ctx context.Context, id string) (bool, error) {
+	var result bool
+	if err := e.CallContext(ctx, &result, "eth_uninstallFilter", id); err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
+
+// PollNewHead delivers the headers of newly imported blocks like
+// SubscribeNewHead, but by polling a block filter over HTTP. Expired filters
+// are reinstalled, and the headers imported in the meantime are delivered.
+// The subscription ends when ctx is cancelled, the returned done channel is
+// closed or five consecutive polls fail, after which the queue is closed.
+func (e *Ginfura) PollNewHead(ctx context.Context) (<-chan Header, chan struct{}, error) {
+	id, err := e.NewBlockFilter(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	last, err := e.GetBlockNumber(ctx)
+	if err != nil {
+		e.uninstallFilter(id)
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	newHeadQueue := make(chan Header)
+	p := &headPoller{e: e, queue: newHeadQueue, last: last}
+	ctx, cancel := pollContext(ctx, done)
+	go func() {
+		defer close(newHeadQueue)
+		defer cancel()
+		e.runFilter(ctx, id, filterPoll{install: p.install, poll: p.poll, resync: p.resync})
+	}()
+
+	return newHeadQueue, done, nil
+}
+
+// PollNewLog delivers the logs matching q like SubscribeNewLog, but by polling
+// a log filter over HTTP. Expired filters are reinstalled, and the logs
+// emitted in the meantime are delivered. The subscription ends when ctx is
+// cancelled, the returned done channel is closed or five consecutive polls
+// fail, after which the queue is closed.
+func (e *Ginfura) PollNewLog(ctx context.Context, q FilterQuery) (<-chan Log, chan struct{}, error) {
+	id, err := e.NewFilter(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	last, err := e.GetBlockNumber(ctx)
+	if err != nil {
+		e.uninstallFilter(id)
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	logQueue := make(chan Log)
+	p := &logPoller{e: e, query: q, queue: logQueue, last: last}
+	ctx, cancel := pollContext(ctx, done)
+	go func() {
+		defer close(logQueue)
+		defer cancel()
+		e.runFilter(ctx, id, filterPoll{install: p.install, poll: p.poll, resync: p.resync})
+	}()
+
+	return logQueue, done, nil
+}
+
+// PollPendingTransaction delivers the hashes of new pending transactions like
+// SubscribePendingTransaction, but by polling a pending transaction filter
+// over HTTP. Expired filters are reinstalled; transactions seen in the
+// meantime are lost. The subscription ends when ctx is cancelled, the
+// returned done channel is closed or five consecutive polls fail, after which
+// the queue is closed.
+func (e *Ginfura) PollPendingTransaction(ctx context.Context) (<-chan Hash, chan struct{}, error) {
+	id, err := e.NewPendingTransactionFilter(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	pendingTxQueue := make(chan Hash)
+	ctx, cancel := pollContext(ctx, done)
+	go func() {
+		defer close(pendingTxQueue)
+		defer cancel()
+		e.runFilter(ctx, id, filterPoll{
+			install: e.NewPendingTransactionFilter,
+			poll: func(ctx context.Context, id string) error {
+				hashes, err := e.GetFilterHashChanges(ctx, id)
+				if err != nil {
+					return err
+				}
+				for _, txHash := range hashes {
+					select {
+					case pendingTxQueue <- txHash:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				return nil
+			},
+		})
+	}()
+
+	return pendingTxQueue, done, nil
+}
+
+// filterPoll describes how a polling subscription installs and polls its
+// filter.
+type filterPoll struct {
+	install func(ctx context.Context) (string, error)
+	// poll fetches the changes of filter id and delivers them.
+	poll func(ctx context.Context, id string) error
+	// resync, if set, delivers what was missed while the filter was gone. It
+	// is called once the filter has been reinstalled.
+	resync func(ctx context.Context) error
+}
+
+// runFilter polls filter id every poll interval until ctx is done,
+// reinstalling it whenever the node reports it expired. Other errors are
+// retried on the next poll, until maxPollFailures polls in a row failed. The
+// filter is uninstalled on return.
+func (e *Ginfura) runFilter(ctx context.Context, id string, f filterPoll) {
+	defer func() { e.uninstallFilter(id) }()
+
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+
+	resync := false
+	failures := 0
+	for failures < maxPollFailures {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if resync {
+			if err := f.resync(ctx); err != nil {
+				failures++
+				continue
+			}
+			resync = false
+		}
+
+		err := f.poll(ctx, id)
+		if err == nil {
+			failures = 0
+			continue
+		}
+		failures++
+
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) || !rpcErr.IsFilterNotFound() {
+			continue
+		}
+		newID, err := f.install(ctx)
+		if err != nil {
+			continue
+		}
+		id = newID
+		resync = f.resync != nil
+	}
+}
+
+// uninstallFilter removes filter id, giving up after unsubscribeTimeout.
+func (e *Ginfura) uninstallFilter(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), unsubscribeTimeout)
+	defer cancel()
+	e.UninstallFilter(ctx, id)
+}
+
+// pollContext returns a context that is also cancelled once done is closed.
+func pollContext(ctx context.Context, done chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// headPoller delivers the headers of the blocks reported by a block filter.
+type headPoller struct {
+	e     *Ginfura
+	queue chan<- Header
+
+	pending []Hash        // reported blocks not yet delivered
+	last    uint64        // number of the highest delivered header
+	seen    map[Hash]bool // blocks delivered by resync since the filter was installed
+}
+
+func (p *headPoller) install(ctx context.Context) (string, error) {
+	p.seen = nil
+	return p.e.NewBlockFilter(ctx)
+}
+
+func (p *headPoller) poll(ctx context.Context, id string) error {
+	if len(p.pending) == 0 {
+		hashes, err := p.e.GetFilterHashChanges(ctx, id)
+		if err != nil {
+			return err
+		}
+		p.pending = hashes
+	}
+
+	for len(p.pending) > 0 {
+		hash := p.pending[0]
+		if !p.seen[hash] {
+			block, err := p.e.GetBlockByHash(ctx, hash)
+			if err != nil && err != ErrNotFound {
+				return err
+			}
+			if err == nil {
+				if err := p.deliver(ctx, block.Header()); err != nil {
+					return err
+				}
+			}
+		}
+		p.pending = p.pending[1:]
+	}
+	return nil
+}
+
+// resync delivers the headers of the blocks imported after the last delivered
+// one.
+func (p *headPoller) resync(ctx context.Context) error {
+	head, err := p.e.GetBlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	for n := p.last + 1; n <= head; n++ {
+		block, err := p.e.GetBlockByNumber(ctx, BlockNumber(n))
+		if err != nil {
+			return err
+		}
+		if err := p.deliver(ctx, block.Header()); err != nil {
+			return err
+		}
+		if p.seen == nil {
+			p.seen = make(map[Hash]bool)
+		}
+		p.seen[block.Hash] = true
+	}
+	return nil
+}
+
+func (p *headPoller) deliver(ctx context.Context, head Header) error {
+	select {
+	case p.queue <- head:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if number := head.Number.Uint64(); number > p.last {
+		p.last = number
+	}
+	return nil
+}
+
+// logPoller delivers the logs reported by a log filter.
+type logPoller struct {
+	e     *Ginfura
+	query FilterQuery
+	queue chan<- Log
+
+	pending   []Log  // reported logs not yet delivered
+	last      uint64 // highest block whose logs were delivered
+	skipUntil uint64 // logs up to this block were delivered by resync
+}
+
+func (p *logPoller) install(ctx context.Context) (string, error) {
+	return p.e.NewFilter(ctx, p.query)
+}
+
+func (p *logPoller) poll(ctx context.Context, id string) error {
+	if len(p.pending) == 0 {
+		logs, err := p.e.GetFilterChanges(ctx, id)
+		if err != nil {
+			return err
+		}
+		p.pending = logs
+	}
+
+	for len(p.pending) > 0 {
+		log := p.pending[0]
+		if log.Removed || log.BlockNumber.Uint64() > p.skipUntil {
+			if err := p.deliver(ctx, log); err != nil {
+				return err
+			}
+		}
+		p.pending = p.pending[1:]
+	}
+	return nil
+}
+
+// resync delivers the logs of the blocks imported after the last delivered
+// ones, up to the end of the queried range.
+func (p *logPoller) resync(ctx context.Context) error {
+	head, err := p.e.GetBlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if to := p.query.ToBlock; to != nil && *to >= 0 && uint64(*to) < head {
+		head = uint64(*to)
+	}
+
+	if p.last < head {
+		q := p.query
+		fromBlock, toBlock := BlockNumber(p.last+1), BlockNumber(head)
+		q.FromBlock, q.ToBlock = &fromBlock, &toBlock
+		err := p.e.ScanLogs(ctx, q, LogScanConfig{}, func(logs []Log) error {
+			for _, log := range logs {
+				if err := p.deliver(ctx, log); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if head > p.last {
+		p.last = head
+	}
+	p.skipUntil = head
+	return nil
+}
+
+func (p *logPoller) deliver(ctx context.Context, log Log) error {
+	select {
+	case p.queue <- log:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if number := log.BlockNumber.Uint64(); number > p.last {
+		p.last = number
+	}
+	return nil
+}
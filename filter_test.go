@@ -0,0 +1,287 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testFilter is a filter installed on filterServer.
+type testFilter struct {
+	blocks bool   // block filter rather than log filter
+	last   uint64 // last block reported
+}
+
+// filterServer emulates the filters of a node whose head advances by one
+// block, with one log, whenever a filter is polled.
+type filterServer struct {
+	mu       sync.Mutex
+	head     uint64
+	filters  map[string]*testFilter
+	nextID   int
+	installs int
+	failures int // number of upcoming polls failing with an internal error
+}
+
+func newFilterServer(t *testing.T, head uint64) (*filterServer, *Ginfura) {
+	t.Helper()
+
+	s := &filterServer{head: head, filters: make(map[string]*testFilter)}
+	srv := newRPCServer(t, s.handle)
+	return s, NewGinfura("mainnet", "", WithURL(srv.URL), WithPollInterval(5*time.Millisecond))
+}
+
+func blockHash(n uint64) Hash {
+	return Hash{0xff, byte(n >> 8), byte(n)}
+}
+
+func testLog(n uint64) Log {
+	return Log{BlockNumber: Quantity(n), BlockHash: blockHash(n)}
+}
+
+func (s *filterServer) handle(method string, params []json.RawMessage) (interface{}, *RPCError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch method {
+	case "eth_blockNumber":
+		return Quantity(s.head), nil
+
+	case "eth_newFilter", "eth_newBlockFilter":
+		s.nextID++
+		id := "0x" + strconv.Itoa(s.nextID)
+		s.filters[id] = &testFilter{blocks: method == "eth_newBlockFilter", last: s.head}
+		s.installs++
+		return id, nil
+
+	case "eth_uninstallFilter":
+		var id string
+		json.Unmarshal(params[0], &id)
+		_, ok := s.filters[id]
+		delete(s.filters, id)
+		return ok, nil
+
+	case "eth_getFilterChanges":
+		if s.failures > 0 {
+			s.failures--
+			return nil, &RPCError{Code: ErrCodeInternalError, Message: "internal error"}
+		}
+		var id string
+		json.Unmarshal(params[0], &id)
+		filter, ok := s.filters[id]
+		if !ok {
+			return nil, &RPCError{Code: ErrCodeServerError, Message: "filter not found"}
+		}
+
+		s.head++
+		logs, hashes := []Log{}, []Hash{}
+		for n := filter.last + 1; n <= s.head; n++ {
+			logs = append(logs, testLog(n))
+			hashes = append(hashes, blockHash(n))
+		}
+		filter.last = s.head
+		if filter.blocks {
+			return hashes, nil
+		}
+		return logs, nil
+
+	case "eth_getLogs":
+		var q struct {
+			FromBlock BlockNumber `json:"fromBlock"`
+			ToBlock   BlockNumber `json:"toBlock"`
+		}
+		json.Unmarshal(params[0], &q)
+		logs := []Log{}
+		for n := uint64(q.FromBlock); n <= uint64(q.ToBlock); n++ {
+			logs = append(logs, testLog(n))
+		}
+		return logs, nil
+
+	case "eth_getBlockByHash":
+		var hash Hash
+		json.Unmarshal(params[0], &hash)
+		return testBlock(uint64(hash[1])<<8 | uint64(hash[2])), nil
+
+	case "eth_getBlockByNumber":
+		var n BlockNumber
+		json.Unmarshal(params[0], &n)
+		return testBlock(uint64(n)), nil
+	}
+	return nil, &RPCError{Code: ErrCodeMethodNotFound, Message: "method not found"}
+}
+
+func testBlock(n uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"number":     Quantity(n),
+		"hash":       blockHash(n),
+		"parentHash": blockHash(n - 1),
+	}
+}
+
+// expire drops every filter while the head advances by three blocks.
+func (s *filterServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filters = make(map[string]*testFilter)
+	s.head += 3
+}
+
+func (s *filterServer) fail(polls int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = polls
+}
+
+func (s *filterServer) stats() (installs, installed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.installs, len(s.filters)
+}
+
+// receiveLog returns the block number of the next log of queue, failing the
+// test if none arrives within a second.
+func receiveLog(t *testing.T, queue <-chan Log) uint64 {
+	t.Helper()
+
+	select {
+	case log, ok := <-queue:
+		if !ok {
+			t.Fatal("queue closed")
+		}
+		return log.BlockNumber.Uint64()
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a log")
+		return 0
+	}
+}
+
+// receiveHead returns the block number of the next header of queue, failing
+// the test if none arrives within a second.
+func receiveHead(t *testing.T, queue <-chan Header) uint64 {
+	t.Helper()
+
+	select {
+	case head, ok := <-queue:
+		if !ok {
+			t.Fatal("queue closed")
+		}
+		return head.Number.Uint64()
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a header")
+		return 0
+	}
+}
+
+func TestPollNewLogReinstall(t *testing.T) {
+	s, g := newFilterServer(t, 10)
+	logs, done, err := g.PollNewLog(context.Background(), FilterQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer close(done)
+
+	want := uint64(11)
+	for ; want <= 13; want++ {
+		if n := receiveLog(t, logs); n != want {
+			t.Fatalf("got log of block %d, want %d", n, want)
+		}
+	}
+
+	// the logs emitted while the filter was gone are delivered once.
+	s.expire()
+	for ; want <= 25; want++ {
+		if n := receiveLog(t, logs); n != want {
+			t.Fatalf("got log of block %d, want %d", n, want)
+		}
+	}
+	if installs, _ := s.stats(); installs != 2 {
+		t.Fatalf("filter installed %d times, want 2", installs)
+	}
+}
+
+func TestPollNewHeadReinstall(t *testing.T) {
+	s, g := newFilterServer(t, 10)
+	heads, done, err := g.PollNewHead(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer close(done)
+
+	want := uint64(11)
+	for ; want <= 13; want++ {
+		if n := receiveHead(t, heads); n != want {
+			t.Fatalf("got header of block %d, want %d", n, want)
+		}
+	}
+
+	s.expire()
+	for ; want <= 25; want++ {
+		if n := receiveHead(t, heads); n != want {
+			t.Fatalf("got header of block %d, want %d", n, want)
+		}
+	}
+	if installs, _ := s.stats(); installs != 2 {
+		t.Fatalf("filter installed %d times, want 2", installs)
+	}
+}
+
+func TestPollTransientErrors(t *testing.T) {
+	s, g := newFilterServer(t, 10)
+	logs, done, err := g.PollNewLog(context.Background(), FilterQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer close(done)
+
+	s.fail(maxPollFailures - 1)
+	for want := uint64(11); want <= 13; want++ {
+		if n := receiveLog(t, logs); n != want {
+			t.Fatalf("got log of block %d, want %d", n, want)
+		}
+	}
+}
+
+func TestPollPersistentErrors(t *testing.T) {
+	s, g := newFilterServer(t, 10)
+	s.fail(maxPollFailures)
+	logs, _, err := g.PollNewLog(context.Background(), FilterQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case log, ok := <-logs:
+		if ok {
+			t.Fatalf("got log of block %d, want the queue closed", log.BlockNumber)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription did not end")
+	}
+	waitFor(t, "the filter to be uninstalled", func() bool {
+		_, installed := s.stats()
+		return installed == 0
+	})
+}
+
+func TestPollDone(t *testing.T) {
+	s, g := newFilterServer(t, 10)
+	heads, done, err := g.PollNewHead(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiveHead(t, heads)
+	close(done)
+
+	for range heads {
+	}
+	waitFor(t, "the filter to be uninstalled", func() bool {
+		_, installed := s.stats()
+		return installed == 0
+	})
+}
@@ -1,8 +1,11 @@
 package ginfura
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/orcaman/concurrent-map"
@@ -11,37 +14,128 @@ import (
 type subscription struct {
 	conn           *websocket.Conn
 	subscriptionID string
+
+	writeMu   sync.Mutex      // serializes writes to conn
+	responses chan *wsMessage // responses to requests sent on conn
+	stopping  chan struct{}   // closed when notifications must no longer be delivered
+	stopOnce  sync.Once
+	closed    chan struct{} // closed once conn is closed
+	closeOnce sync.Once
+}
+
+func newSubscription(conn *websocket.Conn) *subscription {
+	return &subscription{
+		conn:      conn,
+		responses: make(chan *wsMessage, 1),
+		stopping:  make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+}
+
+// write sends msg over the subscription connection.
+func (s *subscription) write(msg *jsonrpcMessage) error {
+	jsonValue, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteMessage(websocket.TextMessage, jsonValue)
+}
+
+// stop stops the delivery of notifications to the subscriber.
+func (s *subscription) stop() {
+	s.stopOnce.Do(func() { close(s.stopping) })
 }
 
 // Ginfura ...
 type Ginfura struct {
+	idCounter uint64 // accessed atomically, must stay 64-bit aligned
+	finalized uint64 // highest finalized block number seen, accessed atomically
+
+	network string
+	chainID uint64 // expected chain id, overrides the network registry
+
 	// Http connection
-	url    string
-	client *http.Client
+	url            string
+	client         *http.Client
+	transport      http.RoundTripper
+	requestTimeout time.Duration
+	header         http.Header // sent with HTTP requests and websocket handshakes
+	retryPolicy    RetryPolicy
+	limiter        *rateLimiter // shared by HTTP and websocket requests
+	failover       *failover    // nil unless created by NewFailoverGinfura
+	cache          Cache
+	coalescer      *coalescer
+	interceptors   []Interceptor // shared by HTTP and websocket requests
+	metrics        Metrics
+
+	// Polling subscriptions
+	pollInterval time.Duration
 
 	// Websocket connection
 	wsURL           string
+	wsDialer        *websocket.Dialer
 	subscriptionMap cmap.ConcurrentMap // SubscriptionType => subscription
 }
 
-// NewGinfura return new instance of ginfura api.
-func NewGinfura(network string, projectID string) *Ginfura {
+// NewGinfura return new instance of ginfura api. Registered networks, see
+// LookupNetwork, may be given by name or alias. Their websocket endpoint uses
+// the wss://<network>.infura.io/ws/v3/<projectID> format; other networks keep
+// the legacy wss://<network>.infura.io/v3/<projectID>/ws one, which can be
+// restored with WithWebsocketURL.
+func NewGinfura(network string, projectID string, opts ...Option) *Ginfura {
 	var url string
 	var wsURL string
-	subsMap := cmap.New()
 
-	if projectID == "" {
+	known, ok := LookupNetwork(network)
+	switch {
+	case ok && projectID != "":
+		network = known.Name
+		url = fmt.Sprintf(known.HTTPURL, projectID)
+		wsURL = fmt.Sprintf(known.WSURL, projectID)
+	case projectID == "":
 		url = fmt.Sprintf("https://%s.infura.io/", network)
 		wsURL = fmt.Sprintf("wss://%s.infura.io/ws", network)
-	} else {
+	default:
 		url = fmt.Sprintf("https://%s.infura.io/v3/%s", network, projectID)
 		wsURL = fmt.Sprintf("wss://%s.infura.io/v3/%s/ws", network, projectID)
 	}
 
-	return &Ginfura{
+	return newGinfura(network, url, wsURL, opts...)
+}
+
+func newGinfura(network, url, wsURL string, opts ...Option) *Ginfura {
+	g := &Ginfura{
+		network:         network,
 		url:             url,
 		wsURL:           wsURL,
 		client:          &http.Client{},
-		subscriptionMap: subsMap,
+		header:          http.Header{},
+		pollInterval:    defaultPollInterval,
+		subscriptionMap: cmap.New(),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if g.transport != nil {
+		client := *g.client
+		client.Transport = g.transport
+		g.client = &client
+	}
+	if g.wsDialer == nil {
+		g.wsDialer = websocketDialer(g.client, g.requestTimeout)
+	}
+
+	return g
+}
+
+// Close stops the background work of the client, such as failover health
+// checks. Open subscriptions are not affected.
+func (g *Ginfura) Close() {
+	if g.failover != nil {
+		g.failover.close()
 	}
 }
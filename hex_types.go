@@ -0,0 +1,148 @@
+package ginfura
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// HashLength is the length in bytes of a hash.
+const HashLength = 32
+
+// Address is a 20-byte Ethereum account address.
+type Address [AddressLength]byte
+
+// Hash is a 32-byte Keccak-256 hash of a block, transaction or log topic.
+type Hash [HashLength]byte
+
+// Quantity is a hex-encoded unsigned integer, such as a block number, nonce
+// or gas amount.
+type Quantity uint64
+
+// Bytes is hex-encoded binary data, such as call data or contract code.
+type Bytes []byte
+
+// HexToAddress parses a hex-encoded address, with or without 0x prefix.
+func HexToAddress(s string) (Address, error) {
+	a := Address{}
+	if !isHexAddress(s) {
+		return a, errNotEthereumAddress
+	}
+	if hasHexPrefix(s) {
+		s = s[2:]
+	}
+	hex.Decode(a[:], []byte(s))
+	return a, nil
+}
+
+// Hex returns the 0x-prefixed hex encoding of a.
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Address) UnmarshalText(input []byte) error {
+	return decodeFixed("address", input, a[:])
+}
+
+// HexToHash parses a hex-encoded hash, with or without 0x prefix.
+func HexToHash(s string) (Hash, error) {
+	h := Hash{}
+	if hasHexPrefix(s) {
+		s = s[2:]
+	}
+	if len(s) != 2*HashLength || !isHex(s) {
+		return h, errNotHash
+	}
+	hex.Decode(h[:], []byte(s))
+	return h, nil
+}
+
+// Hex returns the 0x-prefixed hex encoding of h.
+func (h Hash) Hex() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+func (h Hash) String() string {
+	return h.Hex()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.Hex()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *Hash) UnmarshalText(input []byte) error {
+	return decodeFixed("hash", input, h[:])
+}
+
+// Uint64 returns q as an uint64.
+func (q Quantity) Uint64() uint64 {
+	return uint64(q)
+}
+
+func (q Quantity) String() string {
+	return "0x" + strconv.FormatUint(uint64(q), 16)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (q Quantity) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (q *Quantity) UnmarshalText(input []byte) error {
+	s := string(input)
+	if !hasHexPrefix(s) || len(s) == 2 {
+		return fmt.Errorf("invalid quantity %q", s)
+	}
+	v, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quantity %q", s)
+	}
+	*q = Quantity(v)
+	return nil
+}
+
+func (b Bytes) String() string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *Bytes) UnmarshalText(input []byte) error {
+	s := string(input)
+	if !hasHexPrefix(s) {
+		return fmt.Errorf("invalid hex data %q", s)
+	}
+	decoded, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return fmt.Errorf("invalid hex data %q", s)
+	}
+	*b = decoded
+	return nil
+}
+
+// decodeFixed decodes 0x-prefixed hex input of exactly len(out) bytes.
+func decodeFixed(kind string, input []byte, out []byte) error {
+	s := string(input)
+	if !hasHexPrefix(s) || len(s) != 2+2*len(out) || !isHex(s[2:]) {
+		return fmt.Errorf("invalid %s %q", kind, s)
+	}
+	hex.Decode(out, input[2:])
+	return nil
+}
@@ -1,483 +1,239 @@
 package ginfura
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
-	"strconv"
+	"math/big"
 )
 
-func (e *Ginfura) GetBlockNumber() (uint64, error) {
-	result := response{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_blockNumber",
-		"params":  []interface{}{},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
+func (e *Ginfura) GetBlockNumber(ctx context.Context) (uint64, error) {
+	var result Quantity
+	if err := e.CallContext(ctx, &result, "eth_blockNumber"); err != nil {
 		return 0, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	blkNumber, _ := strconv.ParseUint(result.Result, 0, 64)
-
-	return blkNumber, nil
+	return result.Uint64(), nil
 }
 
-func (e *Ginfura) ProtocolVersion() (string, error) {
-	result := response{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_protocolVersion",
-		"params":  []interface{}{},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
+func (e *Ginfura) ProtocolVersion(ctx context.Context) (string, error) {
+	var result string
+	if err := e.CallContext(ctx, &result, "eth_protocolVersion"); err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
-
-	return result.Result, nil
+	return result, nil
 }
 
 func validateTxCall(txCallObj TransactionCall) bool {
-	if txCallObj.To == "" {
+	if txCallObj.To == nil {
 		return false
 	}
 	return true
 }
 
-func (e *Ginfura) Call(txCallObj TransactionCall, blkParam string) (string, error) {
-	result := response{}
-
-	if _, err := strconv.ParseUint(blkParam, 0, 64); err != nil && blkParam != "latest" && blkParam != "pending" && blkParam != "earliest" {
-		return "", errors.New("Block param should be number or `pending`, `latest`, `earliest`")
-	}
-
+func (e *Ginfura) Call(ctx context.Context, txCallObj TransactionCall, blkParam BlockNumberOrHash) (Bytes, error) {
 	if ok := validateTxCall(txCallObj); !ok {
-		return "", errors.New("Must define `to` field")
+		return nil, errors.New("Must define `to` field")
 	}
 
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_call",
-		"params":  []interface{}{txCallObj, blkParam},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
+	var result Bytes
+	if err := e.CallContext(ctx, &result, "eth_call", txCallObj, blkParam); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
-
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetGasPrice() (uint64, error) {
-	result := response{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_gasPrice",
-		"params":  []interface{}{},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return 0, err
+func (e *Ginfura) GetGasPrice(ctx context.Context) (*big.Int, error) {
+	var result string
+	if err := e.CallContext(ctx, &result, "eth_gasPrice"); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
-
-	gasPrice, _ := strconv.ParseUint(result.Result, 0, 64)
 
-	return gasPrice, nil
+	return parseBig(result)
 }
 
-func (e *Ginfura) GetBalance(address string) (uint64, error) {
-	result := response{}
-
-	if !isHexAddress(address) {
-		return 0, errNotEthereumAddress
-	}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getBalance",
-		"params":  []interface{}{address, "latest"},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return 0, err
+func (e *Ginfura) GetBalance(ctx context.Context, address Address, blkParam BlockNumberOrHash) (*big.Int, error) {
+	var result string
+	if err := e.CallContext(ctx, &result, "eth_getBalance", address, blkParam); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
-
-	balance, _ := strconv.ParseUint(result.Result, 0, 64)
-
-	return balance, nil
+	return parseBig(result)
 }
 
-func (e *Ginfura) GetBlockByHash(blkHash string, showDetail bool) (Block, error) {
-	result := blkInfoResp{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getBlockByHash",
-		"params":  []interface{}{blkHash, showDetail},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
+// GetBlockByHash returns the block with the given hash, listing only the
+// hashes of its transactions.
+func (e *Ginfura) GetBlockByHash(ctx context.Context, blkHash Hash) (Block, error) {
+	result := Block{}
+	if err := e.callObject(ctx, &result, "eth_getBlockByHash", blkHash, false); err != nil {
 		return Block{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetBlockTransactionCountByHash(blkHash string) (string, error) {
-	result := response{}
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getBlockTransactionCountByHash",
-		"params":  []interface{}{blkHash},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
+// GetBlockByHashWithTransactions returns the block with the given hash
+// together with its full transaction objects.
+func (e *Ginfura) GetBlockByHashWithTransactions(ctx context.Context, blkHash Hash) (BlockWithTransactions, error) {
+	result := BlockWithTransactions{}
+	if err := e.callObject(ctx, &result, "eth_getBlockByHash", blkHash, true); err != nil {
+		return BlockWithTransactions{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetBlockTransactionCountByNumber(blkNumber string) (string, error) {
-	result := response{}
-
-	if _, err := strconv.ParseUint(blkNumber, 0, 64); err != nil && blkNumber != "latest" && blkNumber != "pending" && blkNumber != "earliest" {
-		return "", errors.New("Block param should be number or `pending`, `latest`, `earliest`")
-	}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getBlockTransactionCountByNumber",
-		"params":  []interface{}{blkNumber},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
+// GetBlockByNumber returns the block with the given number or tag, listing
+// only the hashes of its transactions.
+func (e *Ginfura) GetBlockByNumber(ctx context.Context, blkNumber BlockNumber) (Block, error) {
+	result := Block{}
+	if err := e.callObject(ctx, &result, "eth_getBlockByNumber", blkNumber, false); err != nil {
+		return Block{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetCode(address, blockParam string) (string, error) {
-	result := response{}
-
-	if !isHexAddress(address) {
-		return "", errNotEthereumAddress
+// GetBlockByNumberWithTransactions returns the block with the given number or
+// tag together with its full transaction objects.
+func (e *Ginfura) GetBlockByNumberWithTransactions(ctx context.Context, blkNumber BlockNumber) (BlockWithTransactions, error) {
+	result := BlockWithTransactions{}
+	if err := e.callObject(ctx, &result, "eth_getBlockByNumber", blkNumber, true); err != nil {
+		return BlockWithTransactions{}, err
 	}
 
-	if _, err := strconv.ParseUint(blockParam, 0, 64); err != nil && blockParam != "latest" && blockParam != "pending" && blockParam != "earliest" {
-		return "", errors.New("Block param should be number or `pending`, `latest`, `earliest`")
-	}
+	return result, nil
+}
 
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getCode",
-		"params":  []interface{}{address, blockParam},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
+func (e *Ginfura) GetBlockTransactionCountByHash(ctx context.Context, blkHash Hash) (uint64, error) {
+	var result Quantity
+	if err := e.callObject(ctx, &result, "eth_getBlockTransactionCountByHash", blkHash); err != nil {
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
-
-	return result.Result, nil
+	return result.Uint64(), nil
 }
 
-func (e *Ginfura) GetTransactionByBlockHashAndIndex(blkHash, index string) (Transaction, error) {
-	result := txInfoResp{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getTransactionByBlockHashAndIndex",
-		"params":  []interface{}{blkHash, index},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return Transaction{}, err
+func (e *Ginfura) GetBlockTransactionCountByNumber(ctx context.Context, blkNumber BlockNumber) (uint64, error) {
+	var result Quantity
+	if err := e.callObject(ctx, &result, "eth_getBlockTransactionCountByNumber", blkNumber); err != nil {
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
-
-	return result.Result, nil
+	return result.Uint64(), nil
 }
 
-func (e *Ginfura) GetTransactionByBlockNumberAndIndex(blkNumber, index string) (Transaction, error) {
-	result := txInfoResp{}
-
-	if _, err := strconv.ParseUint(blkNumber, 0, 64); err != nil && blkNumber != "latest" && blkNumber != "pending" && blkNumber != "earliest" {
-		return Transaction{}, errors.New("Block param should be number or `pending`, `latest`, `earliest`")
+func (e *Ginfura) GetCode(ctx context.Context, address Address, blkParam BlockNumberOrHash) (Bytes, error) {
+	var result Bytes
+	if err := e.CallContext(ctx, &result, "eth_getCode", address, blkParam); err != nil {
+		return nil, err
 	}
 
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getTransactionByBlockNumberAndIndex",
-		"params":  []interface{}{blkNumber, index},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
+	return result, nil
+}
+
+func (e *Ginfura) GetTransactionByBlockHashAndIndex(ctx context.Context, blkHash Hash, index uint64) (Transaction, error) {
+	result := Transaction{}
+	if err := e.callObject(ctx, &result, "eth_getTransactionByBlockHashAndIndex", blkHash, Quantity(index)); err != nil {
 		return Transaction{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetTransactionByHash(txHash string) (Transaction, error) {
-	result := txInfoResp{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getTransactionByHash",
-		"params":  []interface{}{txHash},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
+func (e *Ginfura) GetTransactionByBlockNumberAndIndex(ctx context.Context, blkNumber BlockNumber, index uint64) (Transaction, error) {
+	result := Transaction{}
+	if err := e.callObject(ctx, &result, "eth_getTransactionByBlockNumberAndIndex", blkNumber, Quantity(index)); err != nil {
 		return Transaction{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetTransactionCount(address, blkParams string) (string, error) {
-	result := response{}
-
-	if !isHexAddress(address) {
-		return "", errNotEthereumAddress
+func (e *Ginfura) GetTransactionByHash(ctx context.Context, txHash Hash) (Transaction, error) {
+	result := Transaction{}
+	if err := e.callObject(ctx, &result, "eth_getTransactionByHash", txHash); err != nil {
+		return Transaction{}, err
 	}
 
-	if _, err := strconv.ParseUint(blkParams, 0, 64); err != nil && blkParams != "latest" && blkParams != "pending" && blkParams != "earliest" {
-		return "", errors.New("Block param should be number or `pending`, `latest`, `earliest`")
-	}
+	return result, nil
+}
 
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getTransactionCount",
-		"params":  []interface{}{address, blkParams},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
+func (e *Ginfura) GetTransactionCount(ctx context.Context, address Address, blkParam BlockNumberOrHash) (uint64, error) {
+	var result Quantity
+	if err := e.CallContext(ctx, &result, "eth_getTransactionCount", address, blkParam); err != nil {
+		return 0, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result.Uint64(), nil
 }
 
-func (e *Ginfura) GetTransactionReceipt(txHash string) (TransactionReceipt, error) {
-	result := txReceiptResp{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getTransactionReceipt",
-		"params":  []interface{}{txHash},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
+func (e *Ginfura) GetTransactionReceipt(ctx context.Context, txHash Hash) (TransactionReceipt, error) {
+	result := TransactionReceipt{}
+	if err := e.callObject(ctx, &result, "eth_getTransactionReceipt", txHash); err != nil {
 		return TransactionReceipt{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetUncleByBlockHashAndIndex(blkHash, index string) (UncleBlock, error) {
-	result := uncleBlkResp{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getUncleByBlockHashAndIndex",
-		"params":  []interface{}{blkHash, index},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
+func (e *Ginfura) GetUncleByBlockHashAndIndex(ctx context.Context, blkHash Hash, index uint64) (UncleBlock, error) {
+	result := UncleBlock{}
+	if err := e.callObject(ctx, &result, "eth_getUncleByBlockHashAndIndex", blkHash, Quantity(index)); err != nil {
 		return UncleBlock{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetUncleByBlockNumberAndIndex(blkNumber, index string) (UncleBlock, error) {
-	result := uncleBlkResp{}
-
-	if _, err := strconv.ParseUint(blkNumber, 0, 64); err != nil && blkNumber != "latest" && blkNumber != "pending" && blkNumber != "earliest" {
-		return UncleBlock{}, errors.New("Block param should be number or `pending`, `latest`, `earliest`")
-	}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getUncleByBlockNumberAndIndex",
-		"params":  []interface{}{blkNumber, index},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
+func (e *Ginfura) GetUncleByBlockNumberAndIndex(ctx context.Context, blkNumber BlockNumber, index uint64) (UncleBlock, error) {
+	result := UncleBlock{}
+	if err := e.callObject(ctx, &result, "eth_getUncleByBlockNumberAndIndex", blkNumber, Quantity(index)); err != nil {
 		return UncleBlock{}, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) GetUncleCountByBlockHash(blkHash string) (string, error) {
-	result := response{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getUncleCountByBlockHash",
-		"params":  []interface{}{blkHash},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
+func (e *Ginfura) GetUncleCountByBlockHash(ctx context.Context, blkHash Hash) (uint64, error) {
+	var result Quantity
+	if err := e.callObject(ctx, &result, "eth_getUncleCountByBlockHash", blkHash); err != nil {
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
-
-	return result.Result, nil
+	return result.Uint64(), nil
 }
 
-func (e *Ginfura) GetUncleCountByBlockNumber(blkNumber string) (string, error) {
-	result := response{}
-
-	if _, err := strconv.ParseUint(blkNumber, 0, 64); err != nil && blkNumber != "latest" && blkNumber != "pending" && blkNumber != "earliest" {
-		return "", errors.New("Block param should be number or `pending`, `latest`, `earliest`")
+func (e *Ginfura) GetUncleCountByBlockNumber(ctx context.Context, blkNumber BlockNumber) (uint64, error) {
+	var result Quantity
+	if err := e.callObject(ctx, &result, "eth_getUncleCountByBlockNumber", blkNumber); err != nil {
+		return 0, err
 	}
 
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_getUncleCountByBlockNumber",
-		"params":  []interface{}{blkNumber},
-		"id":      1,
-	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+	return result.Uint64(), nil
+}
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
+func (e *Ginfura) SendRawTransaction(ctx context.Context, rawTx Bytes) (Hash, error) {
+	var result Hash
+	if err := e.CallContext(ctx, &result, "eth_sendRawTransaction", rawTx); err != nil {
+		return Hash{}, err
+	}
 
-	return result.Result, nil
+	return result, nil
 }
 
-func (e *Ginfura) SendRawTransaction(rawTx string) (string, error) {
-	result := response{}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_sendRawTransaction",
-		"params":  []interface{}{rawTx},
-		"id":      1,
+// callObject calls method like CallContext, but returns ErrNotFound instead of
+// leaving result untouched when the node returns null.
+func (e *Ginfura) callObject(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	var raw json.RawMessage
+	if err := e.CallContext(ctx, &raw, method, params...); err != nil {
+		return err
 	}
-	jsonValue, _ := json.Marshal(values)
-	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
+	if isNull(raw) {
+		return ErrNotFound
 	}
-	defer resp.Body.Close()
-
-	body, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(body, &result)
 
-	return result.Result, nil
+	return json.Unmarshal(raw, result)
 }
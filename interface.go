@@ -1,32 +1,63 @@
 package ginfura
 
-import "context"
+import (
+	"context"
+	"math/big"
+)
 
 // IGinfura ...
 type IGinfura interface {
+	// Generic JSON-RPC
+	CallContext(ctx context.Context, result interface{}, method string, params ...interface{}) error
+	BatchCallContext(ctx context.Context, b []BatchElem) error
+	Close()
+
 	// HTTP API
-	GetBlockNumber() (uint64, error)
-	ProtocolVersion() (string, error)
-	Call(txCallObj TransactionCall, blkParam string) (string, error)
-	GetGasPrice() (uint64, error)
-	GetBalance(address string) (uint64, error)
-	GetBlockByHash(blkHash string, showDetail bool) (Block, error)
-	GetBlockTransactionCountByHash(blkHash string) (string, error)
-	GetBlockTransactionCountByNumber(blkHash string) (string, error)
-	GetCode(address string, blkParams string) (string, error)
-	GetTransactionByBlockHashAndIndex(blkHash, txIndex string) (Transaction, error)
-	GetTransactionByBlockNumberAndIndex(blkNumber, txIndex string) (Transaction, error)
-	GetTransactionByHash(txHash string) (Transaction, error)
-	GetTransactionCount(address, blkParams string) (string, error)
-	GetTransactionReceipt(txHash string) (TransactionReceipt, error)
-	GetUncleByBlockHashAndIndex(blkHash, index string) (UncleBlock, error)
-	GetUncleByBlockNumberAndIndex(blkNumber, index string) (UncleBlock, error)
-	GetUncleCountByBlockHash(blkHash string) (string, error)
-	GetUncleCountByBlockNumber(blkNumber string) (string, error)
-	SendRawTransaction(rawTx string) (string, error)
+	ChainID(ctx context.Context) (uint64, error)
+	GetBlockNumber(ctx context.Context) (uint64, error)
+	ProtocolVersion(ctx context.Context) (string, error)
+	Call(ctx context.Context, txCallObj TransactionCall, blkParam BlockNumberOrHash) (Bytes, error)
+	GetGasPrice(ctx context.Context) (*big.Int, error)
+	GetBalance(ctx context.Context, address Address, blkParam BlockNumberOrHash) (*big.Int, error)
+	GetBlockByHash(ctx context.Context, blkHash Hash) (Block, error)
+	GetBlockByHashWithTransactions(ctx context.Context, blkHash Hash) (BlockWithTransactions, error)
+	GetBlockByNumber(ctx context.Context, blkNumber BlockNumber) (Block, error)
+	GetBlockByNumberWithTransactions(ctx context.Context, blkNumber BlockNumber) (BlockWithTransactions, error)
+	GetBlockTransactionCountByHash(ctx context.Context, blkHash Hash) (uint64, error)
+	GetBlockTransactionCountByNumber(ctx context.Context, blkNumber BlockNumber) (uint64, error)
+	GetCode(ctx context.Context, address Address, blkParam BlockNumberOrHash) (Bytes, error)
+	GetTransactionByBlockHashAndIndex(ctx context.Context, blkHash Hash, txIndex uint64) (Transaction, error)
+	GetTransactionByBlockNumberAndIndex(ctx context.Context, blkNumber BlockNumber, txIndex uint64) (Transaction, error)
+	GetTransactionByHash(ctx context.Context, txHash Hash) (Transaction, error)
+	GetTransactionCount(ctx context.Context, address Address, blkParam BlockNumberOrHash) (uint64, error)
+	GetTransactionReceipt(ctx context.Context, txHash Hash) (TransactionReceipt, error)
+	GetUncleByBlockHashAndIndex(ctx context.Context, blkHash Hash, index uint64) (UncleBlock, error)
+	GetUncleByBlockNumberAndIndex(ctx context.Context, blkNumber BlockNumber, index uint64) (UncleBlock, error)
+	GetUncleCountByBlockHash(ctx context.Context, blkHash Hash) (uint64, error)
+	GetUncleCountByBlockNumber(ctx context.Context, blkNumber BlockNumber) (uint64, error)
+	SendRawTransaction(ctx context.Context, rawTx Bytes) (Hash, error)
+	GetLogs(ctx context.Context, q FilterQuery) ([]Log, error)
+	ScanLogs(ctx context.Context, q FilterQuery, cfg LogScanConfig, handle func(logs []Log) error) error
+
+	// Filter API
+	NewFilter(ctx context.Context, q FilterQuery) (string, error)
+	NewBlockFilter(ctx context.Context) (string, error)
+	NewPendingTransactionFilter(ctx context.Context) (string, error)
+	GetFilterChanges(ctx context.Context, id string) ([]Log, error)
+	GetFilterHashChanges(ctx context.Context, id string) ([]Hash, error)
+	GetFilterLogs(ctx context.Context, id string) ([]Log, error)
+	UninstallFilter(ctx context.Context, id string) (bool, error)
+	PollNewHead(ctx context.Context) (<-chan Header, chan struct{}, error)
+	PollNewLog(ctx context.Context, q FilterQuery) (<-chan Log, chan struct{}, error)
+	PollPendingTransaction(ctx context.Context) (<-chan Hash, chan struct{}, error)
 
 	// Websocket API
-	Open() error
-	Close()
-	SubscribePendingTransaction(ctx context.Context)
+	SubscribePendingTransaction(ctx context.Context) (<-chan Hash, chan struct{}, error)
+	UnSubscribePendingTransaction(ctx context.Context) error
+	SubscribeNewHead(ctx context.Context) (<-chan Header, chan struct{}, error)
+	UnSubscribeNewHead(ctx context.Context) error
+	SubscribeNewLog(ctx context.Context, params *LogRequestParams) (<-chan Log, chan struct{}, error)
+	UnSubscribeNewLog(ctx context.Context) error
 }
+
+var _ IGinfura = (*Ginfura)(nil)
@@ -0,0 +1,271 @@
+package ginfura
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// LogScanConfig tunes how ScanLogs splits a block range into eth_getLogs
+// queries. Zero fields take the defaults of DefaultLogScanConfig.
+type LogScanConfig struct {
+	// InitialRange is the number of blocks of the first queries.
+	InitialRange uint64
+	// MaxRange caps the number of blocks of a query when the range grows.
+	MaxRange uint64
+	// SparseResults is the number of logs below which a query is considered
+	// sparse, doubling the range of the following queries.
+	SparseResults int
+	// Concurrency is the maximum number of queries in flight.
+	Concurrency int
+}
+
+// DefaultLogScanConfig returns the config used for unset LogScanConfig fields.
+func DefaultLogScanConfig() LogScanConfig {
+	return LogScanConfig{
+		InitialRange:  2000,
+		MaxRange:      100000,
+		SparseResults: 1000,
+		Concurrency:   4,
+	}
+}
+
+func (cfg LogScanConfig) withDefaults() LogScanConfig {
+	def := DefaultLogScanConfig()
+	if cfg.InitialRange == 0 {
+		cfg.InitialRange = def.InitialRange
+	}
+	if cfg.MaxRange == 0 {
+		cfg.MaxRange = def.MaxRange
+	}
+	if cfg.MaxRange < cfg.InitialRange {
+		cfg.MaxRange = cfg.InitialRange
+	}
+	if cfg.SparseResults == 0 {
+		cfg.SparseResults = def.SparseResults
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = def.Concurrency
+	}
+	return cfg
+}
+
+// ScanLogs queries the logs matching q over its whole block range, splitting
+// it into chunks of adaptive size. A chunk refused for returning too many
+// results or taking too long is bisected until it succeeds, while sparse
+// chunks grow the range of the following ones. Chunks are fetched
+// concurrently, and handle is called with the logs of each chunk in block
+// order. Scanning stops at the first error, including one returned by handle.
+func (e *Ginfura) ScanLogs(ctx context.Context, q FilterQuery, cfg LogScanConfig, handle func(logs []Log) error) error {
+	if q.BlockHash != nil {
+		logs, err := e.GetLogs(ctx, q)
+		if err != nil {
+			return err
+		}
+		return handle(logs)
+	}
+
+	from, err := e.resolveBlockNumber(ctx, q.FromBlock)
+	if err != nil {
+		return err
+	}
+	to, err := e.resolveBlockNumber(ctx, q.ToBlock)
+	if err != nil {
+		return err
+	}
+	if from > to {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cfg = cfg.withDefaults()
+	s := &logScanner{
+		g:     e,
+		query: q,
+		cfg:   cfg,
+		size:  cfg.InitialRange,
+		sem:   make(chan struct{}, cfg.Concurrency),
+	}
+	chunks := make(chan chan logChunk, cfg.Concurrency)
+	go s.dispatch(ctx, from, to, chunks)
+
+	for result := range chunks {
+		var chunk logChunk
+		select {
+		case chunk = <-result:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if chunk.err != nil {
+			return chunk.err
+		}
+		if err := handle(chunk.logs); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// resolveBlockNumber returns the number of the block n refers to. A nil n
+// refers to the latest block.
+func (e *Ginfura) resolveBlockNumber(ctx context.Context, n *BlockNumber) (uint64, error) {
+	if n == nil {
+		return e.GetBlockNumber(ctx)
+	}
+
+	switch *n {
+	case EarliestBlockNumber:
+		return 0, nil
+	case LatestBlockNumber, PendingBlockNumber:
+		return e.GetBlockNumber(ctx)
+	case SafeBlockNumber, FinalizedBlockNumber:
+		block, err := e.GetBlockByNumber(ctx, *n)
+		if err != nil {
+			return 0, err
+		}
+		return block.Number.Uint64(), nil
+	}
+	if *n < 0 {
+		return 0, errInvalidBlockNumber
+	}
+	return uint64(*n), nil
+}
+
+// logChunk is the result of the queries of a chunk of the scanned range.
+type logChunk struct {
+	logs []Log
+	err  error
+}
+
+// logScanner holds the state of a ScanLogs call.
+type logScanner struct {
+	g     *Ginfura
+	query FilterQuery
+	cfg   LogScanConfig
+	sem   chan struct{}
+
+	mu   sync.Mutex
+	size uint64
+}
+
+// dispatch splits [from, to] into chunks and fetches them in the background,
+// queueing their results on chunks in block order.
+func (s *logScanner) dispatch(ctx context.Context, from, to uint64, chunks chan<- chan logChunk) {
+	defer close(chunks)
+
+	for {
+		select {
+		case s.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		end := to
+		if size := s.rangeSize(); to-from >= size {
+			end = from + size - 1
+		}
+		result := make(chan logChunk, 1)
+		go func(from, end uint64) {
+			defer func() { <-s.sem }()
+			logs, err := s.fetch(ctx, from, end)
+			result <- logChunk{logs: logs, err: err}
+		}(from, end)
+
+		select {
+		case chunks <- result:
+		case <-ctx.Done():
+			return
+		}
+		if end == to {
+			return
+		}
+		from = end + 1
+	}
+}
+
+// fetch returns the logs of [from, to], bisecting the range while the node
+// refuses it as too large.
+func (s *logScanner) fetch(ctx context.Context, from, to uint64) ([]Log, error) {
+	q := s.query
+	fromBlock, toBlock := BlockNumber(from), BlockNumber(to)
+	q.FromBlock, q.ToBlock = &fromBlock, &toBlock
+
+	logs, err := s.g.GetLogs(ctx, q)
+	if err == nil {
+		s.observe(to-from+1, len(logs))
+		return logs, nil
+	}
+	if from == to || !isQueryTooLarge(ctx, err) {
+		return nil, err
+	}
+
+	mid := from + (to-from)/2
+	s.shrink(mid - from + 1)
+	left, err := s.fetch(ctx, from, mid)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.fetch(ctx, mid+1, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+func (s *logScanner) rangeSize() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// observe grows the range size when a query of the current size returned
+// sparse results.
+func (s *logScanner) observe(span uint64, results int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if results < s.cfg.SparseResults && span >= s.size {
+		s.size *= 2
+		if s.size > s.cfg.MaxRange {
+			s.size = s.cfg.MaxRange
+		}
+	}
+}
+
+// shrink lowers the range size to span after a query was refused.
+func (s *logScanner) shrink(span uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if span < s.size {
+		s.size = span
+	}
+}
+
+// isQueryTooLarge reports whether err means the queried range should be
+// split, rather than ctx being done or the query being invalid.
+func isQueryTooLarge(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.IsQueryTooLarge()
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusGatewayTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
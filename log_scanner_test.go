@@ -0,0 +1,216 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsQueryTooLarge(t *testing.T) {
+	tests := []struct {
+		err  *RPCError
+		want bool
+	}{
+		{&RPCError{Code: ErrCodeLimitExceeded, Message: "query returned more than 10000 results"}, true},
+		{&RPCError{Code: ErrCodeServerError, Message: "Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"}, true},
+		{&RPCError{Code: ErrCodeServerError, Message: "query timeout exceeded"}, true},
+		{&RPCError{Code: ErrCodeServerError, Message: "block range is too large"}, true},
+		{&RPCError{Code: ErrCodeServerError, Message: "eth_getLogs block range too large, range: 10001, max: 10000"}, true},
+		{&RPCError{Code: ErrCodeServerError, Message: "exceed maximum block range: 5000"}, true},
+		{&RPCError{Code: ErrCodeInvalidParams, Message: "invalid block range params"}, false},
+		{&RPCError{Code: ErrCodeServerError, Message: "fromBlock is greater than toBlock: invalid block range"}, false},
+		{&RPCError{Code: ErrCodeLimitExceeded, Message: "project ID request rate exceeded"}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.err.IsQueryTooLarge(); got != test.want {
+			t.Errorf("IsQueryTooLarge(%q) = %v, want %v", test.err.Message, got, test.want)
+		}
+	}
+}
+
+// logServer serves eth_getLogs with one log per block, refusing queries over
+// more than maxRange blocks. It records the queried ranges and the highest
+// number of queries in flight.
+type logServer struct {
+	maxRange uint64
+	head     uint64
+
+	mu       sync.Mutex
+	ranges   [][2]uint64
+	inFlight int
+	peak     int
+}
+
+func (s *logServer) handle(method string, params []json.RawMessage) (interface{}, *RPCError) {
+	switch method {
+	case "eth_blockNumber":
+		return Quantity(s.head).String(), nil
+	case "eth_getLogs":
+	default:
+		return nil, &RPCError{Code: ErrCodeMethodNotFound, Message: "method not found"}
+	}
+
+	var q struct {
+		FromBlock BlockNumber `json:"fromBlock"`
+		ToBlock   BlockNumber `json:"toBlock"`
+	}
+	if err := json.Unmarshal(params[0], &q); err != nil {
+		return nil, &RPCError{Code: ErrCodeInvalidParams, Message: err.Error()}
+	}
+	from, to := uint64(q.FromBlock), uint64(q.ToBlock)
+
+	s.mu.Lock()
+	s.ranges = append(s.ranges, [2]uint64{from, to})
+	s.inFlight++
+	if s.inFlight > s.peak {
+		s.peak = s.inFlight
+	}
+	s.mu.Unlock()
+
+	// keep the queries in flight long enough to overlap.
+	time.Sleep(2 * time.Millisecond)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+
+	if to < from {
+		return nil, &RPCError{Code: ErrCodeServerError, Message: "fromBlock is greater than toBlock: invalid block range"}
+	}
+	if to-from+1 > s.maxRange {
+		return nil, &RPCError{Code: ErrCodeLimitExceeded, Message: "query returned more than 10000 results"}
+	}
+	logs := []Log{}
+	for n := from; n <= to; n++ {
+		logs = append(logs, Log{BlockNumber: Quantity(n)})
+	}
+	return logs, nil
+}
+
+func blockRange(from, to BlockNumber) FilterQuery {
+	return FilterQuery{FromBlock: &from, ToBlock: &to}
+}
+
+func scanBlocks(t *testing.T, g *Ginfura, q FilterQuery, cfg LogScanConfig) []uint64 {
+	t.Helper()
+
+	var blocks []uint64
+	err := g.ScanLogs(context.Background(), q, cfg, func(logs []Log) error {
+		for _, log := range logs {
+			blocks = append(blocks, log.BlockNumber.Uint64())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blocks
+}
+
+func checkBlocks(t *testing.T, blocks []uint64, from, to uint64) {
+	t.Helper()
+
+	if len(blocks) != int(to-from+1) {
+		t.Fatalf("got logs of %d blocks, want %d", len(blocks), to-from+1)
+	}
+	for i, n := range blocks {
+		if n != from+uint64(i) {
+			t.Fatalf("log #%d is of block %d, want %d", i, n, from+uint64(i))
+		}
+	}
+}
+
+func TestScanLogsBisects(t *testing.T) {
+	s := &logServer{maxRange: 30}
+	g := NewGinfura("mainnet", "", WithURL(newRPCServer(t, s.handle).URL))
+
+	blocks := scanBlocks(t, g, blockRange(1, 500), LogScanConfig{InitialRange: 100, SparseResults: 1, Concurrency: 1})
+	checkBlocks(t, blocks, 1, 500)
+
+	// only the first chunk is bisected: [1, 100], [1, 50] and [51, 100] are
+	// refused, and the following chunks use the shrunk range.
+	refused := 0
+	for _, r := range s.ranges {
+		if r[1]-r[0]+1 > s.maxRange {
+			refused++
+			if r[0] > 100 {
+				t.Fatalf("range %v was refused after the range shrank", r)
+			}
+		}
+	}
+	if refused != 3 {
+		t.Fatalf("%d queries were refused, want 3: %v", refused, s.ranges)
+	}
+}
+
+func TestScanLogsGrowsSparseRanges(t *testing.T) {
+	s := &logServer{maxRange: 1000}
+	g := NewGinfura("mainnet", "", WithURL(newRPCServer(t, s.handle).URL))
+
+	blocks := scanBlocks(t, g, blockRange(0, 799), LogScanConfig{InitialRange: 100, MaxRange: 400, SparseResults: 1000, Concurrency: 1})
+	checkBlocks(t, blocks, 0, 799)
+
+	want := [][2]uint64{{0, 99}, {100, 299}, {300, 699}, {700, 799}}
+	if len(s.ranges) != len(want) {
+		t.Fatalf("queried ranges %v, want %v", s.ranges, want)
+	}
+	for i := range want {
+		if s.ranges[i] != want[i] {
+			t.Fatalf("queried ranges %v, want %v", s.ranges, want)
+		}
+	}
+}
+
+func TestScanLogsConcurrency(t *testing.T) {
+	s := &logServer{maxRange: 1000, head: 999}
+	g := NewGinfura("mainnet", "", WithURL(newRPCServer(t, s.handle).URL))
+
+	earliest := EarliestBlockNumber
+	blocks := scanBlocks(t, g, FilterQuery{FromBlock: &earliest}, LogScanConfig{InitialRange: 10, SparseResults: 1, Concurrency: 3})
+	checkBlocks(t, blocks, 0, 999)
+
+	if s.peak > 3 {
+		t.Fatalf("%d queries were in flight, want at most 3", s.peak)
+	}
+	if s.peak < 2 {
+		t.Fatalf("queries were not concurrent")
+	}
+}
+
+func TestScanLogsInvalidRange(t *testing.T) {
+	var calls int32
+	srv := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, *RPCError) {
+		atomic.AddInt32(&calls, 1)
+		return nil, &RPCError{Code: ErrCodeInvalidParams, Message: "invalid block range params"}
+	})
+	g := NewGinfura("mainnet", "", WithURL(srv.URL))
+
+	err := g.ScanLogs(context.Background(), blockRange(0, 99), LogScanConfig{Concurrency: 1}, func([]Log) error { return nil })
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != ErrCodeInvalidParams {
+		t.Fatalf("err = %v, want the invalid params error", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("calls = %d, want 1", n)
+	}
+}
+
+func TestScanLogsHandlerError(t *testing.T) {
+	s := &logServer{maxRange: 1000}
+	g := NewGinfura("mainnet", "", WithURL(newRPCServer(t, s.handle).URL))
+
+	errStop := errors.New("stop")
+	chunks := 0
+	err := g.ScanLogs(context.Background(), blockRange(0, 999), LogScanConfig{InitialRange: 10, SparseResults: 1}, func([]Log) error {
+		chunks++
+		return errStop
+	})
+	if err != errStop || chunks != 1 {
+		t.Fatalf("ScanLogs = %v after %d chunks, want %v after 1", err, chunks, errStop)
+	}
+}
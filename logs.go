@@ -0,0 +1,72 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// FilterQuery selects the logs returned by GetLogs.
+type FilterQuery struct {
+	// BlockHash restricts the query to a single block. It cannot be combined
+	// with FromBlock or ToBlock.
+	BlockHash *Hash
+	// FromBlock and ToBlock bound the queried range. A nil bound means the
+	// latest block.
+	FromBlock *BlockNumber
+	ToBlock   *BlockNumber
+	// Addresses restricts the query to logs emitted by any of the given
+	// contracts. An empty list matches every contract.
+	Addresses []Address
+	// Topics restricts the query by topic position. A log matches if, for
+	// every position, its topic equals one of the listed hashes. An empty
+	// position matches any topic.
+	//
+	// Examples:
+	//  {}                   matches any topics
+	//  {{A}}                matches A in first position
+	//  {{}, {B}}            matches any topic in first position AND B in second
+	//  {{A}, {B}}           matches A in first position AND B in second
+	//  {{A, B}, {C, D}}     matches (A OR B) in first position AND (C OR D) in second
+	Topics [][]Hash
+}
+
+// MarshalJSON encodes q as the filter object of eth_getLogs and eth_newFilter.
+func (q FilterQuery) MarshalJSON() ([]byte, error) {
+	if q.BlockHash != nil && (q.FromBlock != nil || q.ToBlock != nil) {
+		return nil, errBlockHashWithRange
+	}
+
+	enc := struct {
+		BlockHash *Hash         `json:"blockHash,omitempty"`
+		FromBlock *BlockNumber  `json:"fromBlock,omitempty"`
+		ToBlock   *BlockNumber  `json:"toBlock,omitempty"`
+		Address   []Address     `json:"address,omitempty"`
+		Topics    []interface{} `json:"topics,omitempty"`
+	}{
+		BlockHash: q.BlockHash,
+		FromBlock: q.FromBlock,
+		ToBlock:   q.ToBlock,
+		Address:   q.Addresses,
+	}
+	for _, position := range q.Topics {
+		switch len(position) {
+		case 0:
+			enc.Topics = append(enc.Topics, nil)
+		case 1:
+			enc.Topics = append(enc.Topics, position[0])
+		default:
+			enc.Topics = append(enc.Topics, position)
+		}
+	}
+	return json.Marshal(enc)
+}
+
+// GetLogs returns the logs matching q.
+func (e *Ginfura) GetLogs(ctx context.Context, q FilterQuery) ([]Log, error) {
+	var result []Log
+	if err := e.CallContext(ctx, &result, "eth_getLogs", q); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
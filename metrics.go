@@ -0,0 +1,249 @@
+package ginfura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics receives the measurements of a Ginfura client. Implementations
+// must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest records a completed JSON-RPC request and its error.
+	ObserveRequest(call *RPCCall, err error)
+	// ObserveNotification records a subscription notification received over
+	// a websocket connection.
+	ObserveNotification(subType string)
+	// ObserveDroppedNotification records a subscription notification that
+	// could not be delivered to the subscriber.
+	ObserveDroppedNotification(subType string)
+}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the request
+// latency histogram buckets.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// MetricsRegistry is an in-memory Metrics implementation that serves its
+// measurements in the Prometheus text format, e.g. with
+// mux.Handle("/metrics", registry).
+type MetricsRegistry struct {
+	buckets    []float64
+	creditCost func(method string) int64
+
+	mu            sync.Mutex
+	requests      map[[2]string]uint64 // method, transport
+	latencies     map[string]*histogram
+	errors        map[[2]string]uint64 // method, code
+	credits       map[string]uint64
+	notifications map[string]uint64
+	dropped       map[string]uint64
+}
+
+type histogram struct {
+	counts []uint64 // one per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewMetricsRegistry returns an empty registry. creditCost returns the
+// credits charged for a method; if nil, every request costs one credit.
+func NewMetricsRegistry(creditCost func(method string) int64) *MetricsRegistry {
+	if creditCost == nil {
+		creditCost = func(string) int64 { return 1 }
+	}
+	return &MetricsRegistry{
+		buckets:       DefaultLatencyBuckets,
+		creditCost:    creditCost,
+		requests:      make(map[[2]string]uint64),
+		latencies:     make(map[string]*histogram),
+		errors:        make(map[[2]string]uint64),
+		credits:       make(map[string]uint64),
+		notifications: make(map[string]uint64),
+		dropped:       make(map[string]uint64),
+	}
+}
+
+// ObserveRequest implements Metrics. The calls of a batch are recorded
+// individually with the latency of the whole batch.
+func (r *MetricsRegistry) ObserveRequest(call *RPCCall, err error) {
+	calls := call.Batch
+	if len(calls) == 0 {
+		calls = []*RPCCall{call}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range calls {
+		r.requests[[2]string{c.Method, call.Transport}]++
+		r.credits[c.Method] += uint64(r.creditCost(c.Method))
+		if err != nil {
+			r.errors[[2]string{c.Method, errorCode(err)}]++
+		}
+
+		h, ok := r.latencies[c.Method]
+		if !ok {
+			h = &histogram{counts: make([]uint64, len(r.buckets))}
+			r.latencies[c.Method] = h
+		}
+		seconds := call.Latency.Seconds()
+		for i, bound := range r.buckets {
+			if seconds <= bound {
+				h.counts[i]++
+				break
+			}
+		}
+		h.sum += seconds
+		h.count++
+	}
+}
+
+// ObserveNotification implements Metrics.
+func (r *MetricsRegistry) ObserveNotification(subType string) {
+	r.mu.Lock()
+	r.notifications[subType]++
+	r.mu.Unlock()
+}
+
+// ObserveDroppedNotification implements Metrics.
+func (r *MetricsRegistry) ObserveDroppedNotification(subType string) {
+	r.mu.Lock()
+	r.dropped[subType]++
+	r.mu.Unlock()
+}
+
+// ServeHTTP writes the measurements in the Prometheus text format.
+func (r *MetricsRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// WriteTo writes the measurements in the Prometheus text format to w.
+func (r *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := &strings.Builder{}
+
+	writeHeader(b, "ginfura_requests_total", "counter", "JSON-RPC requests by method and transport.")
+	for _, key := range sortedPairs(r.requests) {
+		fmt.Fprintf(b, "ginfura_requests_total{method=%q,transport=%q} %d\n", key[0], key[1], r.requests[key])
+	}
+
+	writeHeader(b, "ginfura_request_errors_total", "counter", "Failed JSON-RPC requests by method and error code.")
+	for _, key := range sortedPairs(r.errors) {
+		fmt.Fprintf(b, "ginfura_request_errors_total{method=%q,code=%q} %d\n", key[0], key[1], r.errors[key])
+	}
+
+	writeHeader(b, "ginfura_request_duration_seconds", "histogram", "Latency of JSON-RPC requests by method.")
+	methods := make([]string, 0, len(r.latencies))
+	for method := range r.latencies {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		h := r.latencies[method]
+		var cumulative uint64
+		for i, bound := range r.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(b, "ginfura_request_duration_seconds_bucket{method=%q,le=%q} %d\n", method, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(b, "ginfura_request_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", method, h.count)
+		fmt.Fprintf(b, "ginfura_request_duration_seconds_sum{method=%q} %g\n", method, h.sum)
+		fmt.Fprintf(b, "ginfura_request_duration_seconds_count{method=%q} %d\n", method, h.count)
+	}
+
+	writeHeader(b, "ginfura_credits_total", "counter", "Credits used by method.")
+	for _, method := range sortedKeys(r.credits) {
+		fmt.Fprintf(b, "ginfura_credits_total{method=%q} %d\n", method, r.credits[method])
+	}
+
+	writeHeader(b, "ginfura_websocket_notifications_total", "counter", "Subscription notifications received by subscription type.")
+	for _, subType := range sortedKeys(r.notifications) {
+		fmt.Fprintf(b, "ginfura_websocket_notifications_total{subscription=%q} %d\n", subType, r.notifications[subType])
+	}
+
+	writeHeader(b, "ginfura_websocket_dropped_notifications_total", "counter", "Subscription notifications not delivered by subscription type.")
+	for _, subType := range sortedKeys(r.dropped) {
+		fmt.Fprintf(b, "ginfura_websocket_dropped_notifications_total{subscription=%q} %d\n", subType, r.dropped[subType])
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// metricsInterceptor records every request in metrics.
+func metricsInterceptor(metrics Metrics) Interceptor {
+	return func(ctx context.Context, call *RPCCall, next Invoker) error {
+		err := next(ctx, call)
+		metrics.ObserveRequest(call, err)
+		return err
+	}
+}
+
+// errorCode returns the label of err used in metrics.
+func errorCode(err error) string {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return strconv.Itoa(rpcErr.Code)
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return "http_" + strconv.Itoa(httpErr.StatusCode)
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrRateLimited), errors.Is(err, ErrCreditsExhausted):
+		return "rate_limited"
+	}
+	return "transport"
+}
+
+func writeHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPairs(m map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+// observeNotification records a received notification, if metrics are set.
+func (g *Ginfura) observeNotification(subType string, delivered bool) {
+	if g.metrics == nil {
+		return
+	}
+	g.metrics.ObserveNotification(subType)
+	if !delivered {
+		g.metrics.ObserveDroppedNotification(subType)
+	}
+}
+
+var _ Metrics = (*MetricsRegistry)(nil)
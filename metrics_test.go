@@ -0,0 +1,110 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape returns the lines served by registry.
+func scrape(t *testing.T, registry *MetricsRegistry) []string {
+	t.Helper()
+
+	srv := httptest.NewServer(registry)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Fatalf("content type = %q", contentType)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(string(body), "\n")
+}
+
+func checkMetrics(t *testing.T, lines []string, want ...string) {
+	t.Helper()
+
+	for _, metric := range want {
+		found := false
+		for _, line := range lines {
+			if line == metric {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing %s in:\n%s", metric, strings.Join(lines, "\n"))
+		}
+	}
+}
+
+func TestMetricsHistogram(t *testing.T) {
+	registry := NewMetricsRegistry(nil)
+	for _, latency := range []time.Duration{3 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond, 2 * time.Second, 30 * time.Second} {
+		registry.ObserveRequest(&RPCCall{Method: "eth_call", Transport: TransportHTTP, Latency: latency}, nil)
+	}
+
+	checkMetrics(t, scrape(t, registry),
+		"# TYPE ginfura_request_duration_seconds histogram",
+		`ginfura_request_duration_seconds_bucket{method="eth_call",le="0.005"} 1`,
+		`ginfura_request_duration_seconds_bucket{method="eth_call",le="0.01"} 1`,
+		`ginfura_request_duration_seconds_bucket{method="eth_call",le="0.025"} 3`,
+		`ginfura_request_duration_seconds_bucket{method="eth_call",le="1"} 3`,
+		`ginfura_request_duration_seconds_bucket{method="eth_call",le="2.5"} 4`,
+		`ginfura_request_duration_seconds_bucket{method="eth_call",le="10"} 4`,
+		`ginfura_request_duration_seconds_bucket{method="eth_call",le="+Inf"} 5`,
+		`ginfura_request_duration_seconds_sum{method="eth_call"} 32.043`,
+		`ginfura_request_duration_seconds_count{method="eth_call"} 5`,
+		`ginfura_requests_total{method="eth_call",transport="http"} 5`,
+		`ginfura_credits_total{method="eth_call"} 5`,
+	)
+}
+
+func TestMetricsErrors(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		var req jsonrpcMessage
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(rpcError(req.ID, &RPCError{Code: ErrCodeLimitExceeded, Message: "project ID request rate exceeded"}))
+	}))
+	defer srv.Close()
+
+	registry := NewMetricsRegistry(func(method string) int64 { return 80 })
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(RetryPolicy{}), WithMetrics(registry))
+	for i := 0; i < 2; i++ {
+		if _, err := g.GetBlockNumber(context.Background()); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	registry.ObserveNotification(NewHead)
+	registry.ObserveNotification(NewHead)
+	registry.ObserveDroppedNotification(NewHead)
+
+	checkMetrics(t, scrape(t, registry),
+		"# TYPE ginfura_request_errors_total counter",
+		`ginfura_request_errors_total{method="eth_blockNumber",code="-32005"} 1`,
+		`ginfura_request_errors_total{method="eth_blockNumber",code="http_429"} 1`,
+		`ginfura_requests_total{method="eth_blockNumber",transport="http"} 2`,
+		`ginfura_credits_total{method="eth_blockNumber"} 160`,
+		`ginfura_websocket_notifications_total{subscription="newHead"} 2`,
+		"# TYPE ginfura_websocket_dropped_notifications_total counter",
+		`ginfura_websocket_dropped_notifications_total{subscription="newHead"} 1`,
+	)
+}
@@ -0,0 +1,75 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// transports of an RPCCall
+const (
+	TransportHTTP      = "http"
+	TransportWebsocket = "websocket"
+)
+
+// RPCCall describes a JSON-RPC request as seen by interceptors.
+type RPCCall struct {
+	Method    string
+	Params    []interface{}
+	Transport string
+	// Batch holds the calls of a batch request, whose Method is empty.
+	Batch []*RPCCall
+
+	// Response is the raw response, set once the request completes. For HTTP
+	// requests it is also set when the endpoint answers with a non-2xx status.
+	Response json.RawMessage
+	// Latency is the duration of the request including retries, set once
+	// the request completes.
+	Latency time.Duration
+}
+
+// Invoker sends the request described by call.
+type Invoker func(ctx context.Context, call *RPCCall) error
+
+// Interceptor wraps the sending of every HTTP and websocket JSON-RPC request.
+// It must call next to send the request, and may inspect call and the
+// returned error afterwards.
+type Interceptor func(ctx context.Context, call *RPCCall, next Invoker) error
+
+// newRPCCall describes req, a single message or a batch, sent over transport.
+func newRPCCall(transport string, req interface{}) *RPCCall {
+	call := &RPCCall{Transport: transport}
+	switch req := req.(type) {
+	case *jsonrpcMessage:
+		call.Method = req.Method
+		call.Params = req.Params
+	case []*jsonrpcMessage:
+		for _, msg := range req {
+			call.Batch = append(call.Batch, &RPCCall{
+				Method:    msg.Method,
+				Params:    msg.Params,
+				Transport: transport,
+			})
+		}
+	}
+	return call
+}
+
+// intercept sends call through the interceptor chain, the first interceptor
+// being the outermost, and finally invoke. The latency of invoke is recorded
+// on call.
+func (e *Ginfura) intercept(ctx context.Context, call *RPCCall, invoke Invoker) error {
+	next := func(ctx context.Context, call *RPCCall) error {
+		start := time.Now()
+		defer func() { call.Latency = time.Since(start) }()
+		return invoke(ctx, call)
+	}
+
+	for i := len(e.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := e.interceptors[i], next
+		next = func(ctx context.Context, call *RPCCall) error {
+			return interceptor(ctx, call, inner)
+		}
+	}
+	return next(ctx, call)
+}
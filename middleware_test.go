@@ -0,0 +1,40 @@
+package ginfura
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInterceptorResponse(t *testing.T) {
+	status, body := http.StatusOK, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	var response string
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(RetryPolicy{}),
+		WithInterceptors(func(ctx context.Context, call *RPCCall, next Invoker) error {
+			err := next(ctx, call)
+			response = string(call.Response)
+			return err
+		}))
+
+	if _, err := g.GetBlockNumber(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if response != body {
+		t.Fatalf("response = %q, want %q", response, body)
+	}
+
+	status, body = http.StatusForbidden, `{"jsonrpc":"2.0","id":2,"error":{"code":-32002,"message":"rejected due to project ID settings"}}`
+	if _, err := g.GetBlockNumber(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if response != body {
+		t.Fatalf("response = %q, want %q", response, body)
+	}
+}
@@ -0,0 +1,228 @@
+package ginfura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Network is an Infura network and the chain it serves.
+type Network struct {
+	Name    string
+	ChainID uint64
+	// HTTPURL and WSURL are endpoint templates formatted with the project ID.
+	// The built-in networks use Infura's wss://<network>.infura.io/ws/v3/%s
+	// websocket format.
+	HTTPURL string
+	WSURL   string
+}
+
+// ChainMismatchError is returned when an endpoint serves another chain than
+// the configured one.
+type ChainMismatchError struct {
+	Network  string
+	Expected uint64
+	Actual   uint64
+}
+
+func (err *ChainMismatchError) Error() string {
+	if err.Network == "" {
+		return fmt.Sprintf("expected chain id %d, endpoint serves chain id %d", err.Expected, err.Actual)
+	}
+	return fmt.Sprintf("network %q expects chain id %d, endpoint serves chain id %d", err.Network, err.Expected, err.Actual)
+}
+
+var (
+	networksMu sync.RWMutex
+	networks   = map[string]Network{}
+
+	// networkAliases maps short names to the Infura network name.
+	networkAliases = map[string]string{
+		"ethereum":  "mainnet",
+		"polygon":   "polygon-mainnet",
+		"arbitrum":  "arbitrum-mainnet",
+		"optimism":  "optimism-mainnet",
+		"base":      "base-mainnet",
+		"linea":     "linea-mainnet",
+		"avalanche": "avalanche-mainnet",
+		"blast":     "blast-mainnet",
+		"scroll":    "scroll-mainnet",
+		"zksync":    "zksync-mainnet",
+		"celo":      "celo-mainnet",
+		"bsc":       "bsc-mainnet",
+		"mantle":    "mantle-mainnet",
+	}
+)
+
+func init() {
+	chainIDs := map[string]uint64{
+		"mainnet":           1,
+		"sepolia":           11155111,
+		"holesky":           17000,
+		"hoodi":             560048,
+		"polygon-mainnet":   137,
+		"polygon-amoy":      80002,
+		"arbitrum-mainnet":  42161,
+		"arbitrum-sepolia":  421614,
+		"optimism-mainnet":  10,
+		"optimism-sepolia":  11155420,
+		"base-mainnet":      8453,
+		"base-sepolia":      84532,
+		"linea-mainnet":     59144,
+		"linea-sepolia":     59141,
+		"avalanche-mainnet": 43114,
+		"avalanche-fuji":    43113,
+		"blast-mainnet":     81457,
+		"blast-sepolia":     168587773,
+		"scroll-mainnet":    534352,
+		"scroll-sepolia":    534351,
+		"zksync-mainnet":    324,
+		"zksync-sepolia":    300,
+		"celo-mainnet":      42220,
+		"bsc-mainnet":       56,
+		"mantle-mainnet":    5000,
+		"mantle-sepolia":    5003,
+	}
+	for name, chainID := range chainIDs {
+		RegisterNetwork(Network{
+			Name:    name,
+			ChainID: chainID,
+			HTTPURL: "https://" + name + ".infura.io/v3/%s",
+			WSURL:   "wss://" + name + ".infura.io/ws/v3/%s",
+		})
+	}
+}
+
+// RegisterNetwork adds or replaces a network of the registry.
+func RegisterNetwork(network Network) {
+	networksMu.Lock()
+	networks[network.Name] = network
+	networksMu.Unlock()
+}
+
+// LookupNetwork returns the registered network with the given name or alias.
+func LookupNetwork(name string) (Network, bool) {
+	networksMu.RLock()
+	defer networksMu.RUnlock()
+
+	if alias, ok := networkAliases[name]; ok {
+		name = alias
+	}
+	network, ok := networks[name]
+	return network, ok
+}
+
+// Networks returns the registered networks sorted by name.
+func Networks() []Network {
+	networksMu.RLock()
+	defer networksMu.RUnlock()
+
+	list := make([]Network, 0, len(networks))
+	for _, network := range networks {
+		list = append(list, network)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// DialGinfura returns a new instance of ginfura api after checking that the
+// endpoint serves the chain of network. The network must be registered
+// unless the expected chain is set with WithChainID.
+func DialGinfura(ctx context.Context, network string, projectID string, opts ...Option) (*Ginfura, error) {
+	g := NewGinfura(network, projectID, opts...)
+	if err := g.VerifyChainID(ctx); err != nil {
+		g.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+// ChainID returns the chain id served by the endpoint, using net_version
+// for nodes that do not support eth_chainId.
+func (e *Ginfura) ChainID(ctx context.Context) (uint64, error) {
+	return chainID(func(result *string, method string) error {
+		return e.CallContext(ctx, result, method)
+	})
+}
+
+// chainID returns the chain id reported through call, using net_version for
+// nodes that do not support eth_chainId.
+func chainID(call func(result *string, method string) error) (uint64, error) {
+	var result string
+	err := call(&result, "eth_chainId")
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == ErrCodeMethodNotFound {
+		if err := call(&result, "net_version"); err != nil {
+			return 0, err
+		}
+		return strconv.ParseUint(result, 10, 64)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(result, 0, 64)
+}
+
+// VerifyChainID returns a *ChainMismatchError if the endpoint serves another
+// chain than the configured one.
+//
+// Every endpoint of a failover client is checked, skipping the unreachable
+// ones. Unless the chain is set with WithChainID, they must all serve the
+// chain of the first endpoint that answers.
+func (e *Ginfura) VerifyChainID(ctx context.Context) error {
+	expected := e.chainID
+	if expected == 0 && (e.failover == nil || e.network != "") {
+		network, ok := LookupNetwork(e.network)
+		if !ok {
+			return errUnknownNetwork
+		}
+		expected = network.ChainID
+	}
+
+	if e.failover != nil {
+		return e.verifyEndpoints(ctx, expected)
+	}
+
+	actual, err := e.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return &ChainMismatchError{Network: e.network, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// verifyEndpoints checks the chain id of every failover endpoint against
+// expected, or against the first answer if expected is zero. It only fails
+// with a transport error if no endpoint answered.
+func (e *Ginfura) verifyEndpoints(ctx context.Context, expected uint64) error {
+	var lastErr error
+	answered := false
+	for _, endpoint := range e.failover.all() {
+		actual, err := chainID(func(result *string, method string) error {
+			return e.callEndpoint(ctx, endpoint, result, method)
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if expected == 0 {
+			expected = actual
+		}
+		if actual != expected {
+			return &ChainMismatchError{Network: e.network, Expected: expected, Actual: actual}
+		}
+		answered = true
+	}
+
+	if !answered {
+		return lastErr
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package ginfura
+
+import "testing"
+
+func TestLookupNetwork(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		chainID uint64
+	}{
+		{"mainnet", "mainnet", 1},
+		{"ethereum", "mainnet", 1},
+		{"sepolia", "sepolia", 11155111},
+		{"hoodi", "hoodi", 560048},
+		{"polygon", "polygon-mainnet", 137},
+	}
+
+	for _, test := range tests {
+		network, ok := LookupNetwork(test.name)
+		if !ok || network.Name != test.network || network.ChainID != test.chainID {
+			t.Errorf("LookupNetwork(%q) = %+v, %v", test.name, network, ok)
+		}
+	}
+
+	g := NewGinfura("hoodi", "key")
+	if g.url != "https://hoodi.infura.io/v3/key" || g.wsURL != "wss://hoodi.infura.io/ws/v3/key" {
+		t.Errorf("hoodi endpoints = %s, %s", g.url, g.wsURL)
+	}
+}
@@ -0,0 +1,161 @@
+package ginfura
+
+import (
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Option configures a Ginfura instance created by NewGinfura.
+type Option func(*Ginfura)
+
+// WithURL overrides the HTTP JSON-RPC endpoint, e.g. for a local node.
+func WithURL(url string) Option {
+	return func(g *Ginfura) {
+		g.url = url
+	}
+}
+
+// WithWebsocketURL overrides the websocket JSON-RPC endpoint.
+func WithWebsocketURL(wsURL string) Option {
+	return func(g *Ginfura) {
+		g.wsURL = wsURL
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for JSON-RPC requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(g *Ginfura) {
+		g.client = client
+	}
+}
+
+// WithTransport sets the transport of the HTTP client. The client passed to
+// WithHTTPClient is copied rather than modified.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(g *Ginfura) {
+		g.transport = transport
+	}
+}
+
+// WithWebsocketDialer sets the dialer of websocket connections. By default
+// they are dialed with the proxy, TLS config and dial function of the HTTP
+// transport when it is an *http.Transport, and the handshake is bound by the
+// request timeout.
+func WithWebsocketDialer(dialer *websocket.Dialer) Option {
+	return func(g *Ginfura) {
+		g.wsDialer = dialer
+	}
+}
+
+// WithRequestTimeout bounds the duration of every HTTP request and websocket
+// handshake. A zero timeout leaves requests bound only by their context.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(g *Ginfura) {
+		g.requestTimeout = timeout
+	}
+}
+
+// WithHeader adds a header sent with every HTTP request and websocket
+// handshake.
+func WithHeader(key, value string) Option {
+	return func(g *Ginfura) {
+		g.header.Add(key, value)
+	}
+}
+
+// WithProjectSecret authenticates requests with the Infura project secret
+// using HTTP basic auth.
+func WithProjectSecret(secret string) Option {
+	return func(g *Ginfura) {
+		auth := base64.StdEncoding.EncodeToString([]byte(":" + secret))
+		g.header.Set("Authorization", "Basic "+auth)
+	}
+}
+
+// WithJWT authenticates requests with a JSON Web Token signed by a key
+// registered in the Infura project settings.
+func WithJWT(token string) Option {
+	return func(g *Ginfura) {
+		g.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithRetryPolicy retries transient HTTP failures according to policy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(g *Ginfura) {
+		g.retryPolicy = policy
+	}
+}
+
+// WithRateLimit paces requests on the client side to stay within the limits
+// of the Infura plan.
+func WithRateLimit(limit RateLimit) Option {
+	return func(g *Ginfura) {
+		g.limiter = newRateLimiter(limit)
+	}
+}
+
+// WithCache caches the responses of immutable chain data, such as blocks
+// fetched by hash and transactions and receipts of finalized blocks, in
+// cache.
+func WithCache(cache Cache) Option {
+	return func(g *Ginfura) {
+		g.cache = cache
+	}
+}
+
+// WithCoalescing collapses identical concurrent calls into a single request
+// whose result is shared by all callers. A positive ttl also reuses the
+// result of head-dependent calls, such as eth_blockNumber, for that long
+// after they complete.
+func WithCoalescing(ttl time.Duration) Option {
+	return func(g *Ginfura) {
+		g.coalescer = newCoalescer(ttl)
+	}
+}
+
+// WithInterceptors appends interceptors to the chain wrapping every HTTP and
+// websocket JSON-RPC request, e.g. for logging, metrics or tracing.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(g *Ginfura) {
+		g.interceptors = append(g.interceptors, interceptors...)
+	}
+}
+
+// WithMetrics records the requests and subscription notifications of the
+// client in metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(g *Ginfura) {
+		g.metrics = metrics
+		g.interceptors = append(g.interceptors, metricsInterceptor(metrics))
+	}
+}
+
+// WithTracer wraps every HTTP request and websocket subscribe and unsubscribe
+// request in a span of tracer.
+func WithTracer(tracer Tracer) Option {
+	return func(g *Ginfura) {
+		g.interceptors = append(g.interceptors, tracingInterceptor(tracer, g.network))
+	}
+}
+
+// WithChainID sets the chain id VerifyChainID expects, e.g. for a custom
+// endpoint or a network missing from the registry.
+func WithChainID(chainID uint64) Option {
+	return func(g *Ginfura) {
+		g.chainID = chainID
+	}
+}
+
+// WithPollInterval sets how often polling subscriptions, such as PollNewHead,
+// query their filter.
+func WithPollInterval(interval time.Duration) Option {
+	return func(g *Ginfura) {
+		if interval > 0 {
+			g.pollInterval = interval
+		}
+	}
+}
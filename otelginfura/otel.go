@@ -0,0 +1,63 @@
+// Package otelginfura adapts OpenTelemetry tracing to ginfura.Tracer.
+package otelginfura
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ldmtam/ginfura"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/ldmtam/ginfura"
+
+// Tracer is a ginfura.Tracer creating OpenTelemetry client spans.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a tracer creating spans with the given provider, e.g.
+// otel.GetTracerProvider().
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// Start implements ginfura.Tracer.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, ginfura.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, &otelSpan{span: span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttribute(key string, value interface{}) {
+	switch value := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, value))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, value))
+	case int64:
+		s.span.SetAttributes(attribute.Int64(key, value))
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, value))
+	case float64:
+		s.span.SetAttributes(attribute.Float64(key, value))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+	}
+}
+
+func (s *otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+var _ ginfura.Tracer = (*Tracer)(nil)
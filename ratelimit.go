@@ -0,0 +1,138 @@
+package ginfura
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit configures the client-side rate limiter shared by the HTTP and
+// websocket requests of a Ginfura instance. Websocket eth_unsubscribe requests
+// are not limited, so that subscriptions can always be closed.
+type RateLimit struct {
+	// RequestsPerSecond is the sustained request rate. Zero disables the
+	// per-second limit.
+	RequestsPerSecond float64
+	// Burst is the number of requests that may be sent at once. It defaults
+	// to RequestsPerSecond rounded up.
+	Burst int
+	// CreditsPerDay is the daily credit budget of the Infura plan, reset at
+	// midnight UTC. Zero disables the daily limit.
+	CreditsPerDay int64
+	// CreditCost returns the credits charged for a method. It defaults to one
+	// credit per request.
+	CreditCost func(method string) int64
+	// FailFast makes requests fail with ErrRateLimited or ErrCreditsExhausted
+	// instead of waiting for capacity.
+	FailFast bool
+}
+
+// rateLimiter is a token bucket combined with a daily credit budget.
+type rateLimiter struct {
+	cfg RateLimit
+
+	mu          sync.Mutex
+	tokens      float64
+	lastRefill  time.Time
+	creditsUsed int64
+	day         time.Time // start of the current credit day
+}
+
+func newRateLimiter(cfg RateLimit) *rateLimiter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = int(math.Ceil(cfg.RequestsPerSecond))
+	}
+	if cfg.CreditCost == nil {
+		cfg.CreditCost = func(string) int64 { return 1 }
+	}
+
+	now := time.Now()
+	return &rateLimiter{
+		cfg:        cfg,
+		tokens:     float64(cfg.Burst),
+		lastRefill: now,
+		day:        startOfDay(now),
+	}
+}
+
+// wait blocks until a request for method may be sent, or fails immediately
+// in fail-fast mode. A nil limiter never blocks.
+func (l *rateLimiter) wait(ctx context.Context, method string) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		delay, err := l.reserve(method)
+		if err != nil || delay == 0 {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// waitFor waits for every call of req, a single message or a batch.
+func (l *rateLimiter) waitFor(ctx context.Context, req interface{}) error {
+	switch req := req.(type) {
+	case *jsonrpcMessage:
+		return l.wait(ctx, req.Method)
+	case []*jsonrpcMessage:
+		for _, msg := range req {
+			if err := l.wait(ctx, msg.Method); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reserve takes capacity for a request of method if available. Otherwise it
+// returns how long to wait before trying again.
+func (l *rateLimiter) reserve(method string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if day := startOfDay(now); day.After(l.day) {
+		l.day = day
+		l.creditsUsed = 0
+	}
+
+	cost := l.cfg.CreditCost(method)
+	if l.cfg.CreditsPerDay > 0 && l.creditsUsed+cost > l.cfg.CreditsPerDay {
+		if l.cfg.FailFast {
+			return 0, ErrCreditsExhausted
+		}
+		return l.day.Add(24 * time.Hour).Sub(now), nil
+	}
+
+	if l.cfg.RequestsPerSecond > 0 {
+		elapsed := now.Sub(l.lastRefill).Seconds()
+		l.tokens = math.Min(float64(l.cfg.Burst), l.tokens+elapsed*l.cfg.RequestsPerSecond)
+		l.lastRefill = now
+
+		if l.tokens < 1 {
+			if l.cfg.FailFast {
+				return 0, ErrRateLimited
+			}
+			return time.Duration((1 - l.tokens) / l.cfg.RequestsPerSecond * float64(time.Second)), nil
+		}
+		l.tokens--
+	}
+
+	l.creditsUsed += cost
+	return 0, nil
+}
+
+// startOfDay returns midnight UTC of the day of t.
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
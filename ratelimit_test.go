@@ -0,0 +1,147 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func blockNumberServer(t *testing.T, calls *int32) string {
+	t.Helper()
+
+	srv := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, *RPCError) {
+		atomic.AddInt32(calls, 1)
+		return "0x1", nil
+	})
+	return srv.URL
+}
+
+func TestRateLimitBlocks(t *testing.T) {
+	var calls int32
+	g := NewGinfura("mainnet", "", WithURL(blockNumberServer(t, &calls)), WithRateLimit(RateLimit{
+		RequestsPerSecond: 20,
+		Burst:             2,
+	}))
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := g.GetBlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the burst is sent at once, the two other requests wait 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("4 requests took %v, want at least 100ms", elapsed)
+	}
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Fatalf("calls = %d, want 4", n)
+	}
+}
+
+func TestRateLimitContextCancelled(t *testing.T) {
+	var calls int32
+	g := NewGinfura("mainnet", "", WithURL(blockNumberServer(t, &calls)), WithRateLimit(RateLimit{
+		RequestsPerSecond: 0.1,
+		Burst:             1,
+	}))
+	if _, err := g.GetBlockNumber(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.GetBlockNumber(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("calls = %d, want 1", n)
+	}
+}
+
+func TestRateLimitFailFast(t *testing.T) {
+	var calls int32
+	g := NewGinfura("mainnet", "", WithURL(blockNumberServer(t, &calls)), WithRateLimit(RateLimit{
+		RequestsPerSecond: 1,
+		Burst:             2,
+		FailFast:          true,
+	}))
+
+	for i := 0; i < 2; i++ {
+		if _, err := g.GetBlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now()
+	if _, err := g.GetBlockNumber(context.Background()); err != ErrRateLimited {
+		t.Fatalf("err = %v, want %v", err, ErrRateLimited)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("fail-fast request waited %v", elapsed)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("calls = %d, want 2", n)
+	}
+}
+
+func TestRateLimitCredits(t *testing.T) {
+	var calls int32
+	g := NewGinfura("mainnet", "", WithURL(blockNumberServer(t, &calls)), WithRateLimit(RateLimit{
+		CreditsPerDay: 100,
+		CreditCost: func(method string) int64 {
+			if method == "eth_blockNumber" {
+				return 40
+			}
+			return 1
+		},
+		FailFast: true,
+	}))
+
+	for i := 0; i < 2; i++ {
+		if _, err := g.GetBlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := g.GetBlockNumber(context.Background()); err != ErrCreditsExhausted {
+		t.Fatalf("err = %v, want %v", err, ErrCreditsExhausted)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("calls = %d, want 2", n)
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	l := newRateLimiter(RateLimit{RequestsPerSecond: 10, CreditsPerDay: 5})
+	if l.cfg.Burst != 10 {
+		t.Fatalf("burst = %d, want 10", l.cfg.Burst)
+	}
+
+	for i := 0; i < 5; i++ {
+		if delay, err := l.reserve("eth_call"); delay != 0 || err != nil {
+			t.Fatalf("reserve #%d = %v, %v", i, delay, err)
+		}
+	}
+	// the credits are exhausted until midnight UTC.
+	before := time.Now()
+	delay, err := l.reserve("eth_call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := l.day.Add(24 * time.Hour).Sub(before); delay <= 0 || delay > want {
+		t.Fatalf("delay = %v, want up to %v", delay, want)
+	}
+
+	// a new day resets the credits.
+	l.day = l.day.Add(-24 * time.Hour)
+	if delay, err := l.reserve("eth_call"); delay != 0 || err != nil {
+		t.Fatalf("reserve on a new day = %v, %v", delay, err)
+	}
+}
+
+func TestRateLimiterNil(t *testing.T) {
+	var l *rateLimiter
+	if err := l.wait(context.Background(), "eth_call"); err != nil {
+		t.Fatal(err)
+	}
+}
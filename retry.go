@@ -0,0 +1,195 @@
+package ginfura
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how failed HTTP requests are retried. The zero value
+// disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts per request, including the first
+	// one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts. A Retry-After header
+	// sent by the endpoint takes precedence.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after each retry. Values below 1 are treated
+	// as 1, keeping the delay constant.
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it.
+	Jitter float64
+	// RetryNonIdempotent allows retrying methods that are unsafe to repeat,
+	// such as eth_sendRawTransaction, even when the request may have reached
+	// the node. Without it they are only retried when the endpoint certainly
+	// did not process them.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a retry policy suited to Infura endpoints.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// nonIdempotentMethods lists the methods that must not be repeated blindly.
+var nonIdempotentMethods = map[string]bool{
+	"eth_sendRawTransaction": true,
+	"eth_sendTransaction":    true,
+
+	// filters are installed and consumed on the node
+	"eth_newFilter":                   true,
+	"eth_newBlockFilter":              true,
+	"eth_newPendingTransactionFilter": true,
+	"eth_getFilterChanges":            true,
+}
+
+// backoff returns the delay to wait after the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// withRetry calls send until it succeeds, fails permanently or the attempt
+// budget of the policy is exhausted.
+func (p RetryPolicy) withRetry(ctx context.Context, idempotent bool, send func() error) error {
+	idempotent = idempotent || p.RetryNonIdempotent
+
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		if !isRetryable(err) || (!idempotent && !notProcessed(err)) {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if after := retryAfter(err); after > delay {
+			delay = after
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isIdempotent reports whether req, a single message or a batch, only holds
+// calls that are safe to repeat.
+func isIdempotent(req interface{}) bool {
+	switch req := req.(type) {
+	case *jsonrpcMessage:
+		return !nonIdempotentMethods[req.Method]
+	case []*jsonrpcMessage:
+		for _, msg := range req {
+			if nonIdempotentMethods[msg.Method] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isRetryable reports whether err is a transient failure: a retryable HTTP
+// status, rate limiting, a network timeout, or a connection that was refused,
+// reset or closed early. Other transport errors, such as TLS certificate,
+// unsupported scheme or unknown host errors, are permanent.
+func isRetryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+			http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.IsLimitExceeded() && !rpcErr.IsQueryTooLarge()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// notProcessed reports whether err guarantees the endpoint did not act on
+// the request, so that even non-idempotent calls can be retried.
+func notProcessed(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.IsLimitExceeded() && !rpcErr.IsQueryTooLarge()
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryAfter returns the delay requested by the endpoint, if any.
+func retryAfter(err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
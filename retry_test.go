@@ -0,0 +1,248 @@
+package ginfura
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func urlError(err error) error {
+	return &url.Error{Op: "Post", URL: "https://mainnet.infura.io/v3/x", Err: err}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"service unavailable", &HTTPError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"too many requests", &HTTPError{StatusCode: http.StatusTooManyRequests}, true},
+		{"gateway timeout", &HTTPError{StatusCode: http.StatusGatewayTimeout}, true},
+		{"bad request", &HTTPError{StatusCode: http.StatusBadRequest}, false},
+		{"unauthorized", &HTTPError{StatusCode: http.StatusUnauthorized}, false},
+		{"limit exceeded", &RPCError{Code: ErrCodeLimitExceeded, Message: "project ID request rate exceeded"}, true},
+		{"query too large", &RPCError{Code: ErrCodeLimitExceeded, Message: "query returned more than 10000 results"}, false},
+		{"execution reverted", &RPCError{Code: ErrCodeExecutionReverted, Message: "execution reverted"}, false},
+		{"timeout", urlError(timeoutError{}), true},
+		{"deadline exceeded", urlError(context.DeadlineExceeded), true},
+		{"connection reset", urlError(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"connection refused", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"eof", urlError(io.EOF), true},
+		{"unexpected eof", urlError(io.ErrUnexpectedEOF), true},
+		{"unknown authority", urlError(x509.UnknownAuthorityError{}), false},
+		{"hostname mismatch", urlError(x509.HostnameError{Host: "mainnet.infura.io"}), false},
+		{"unsupported scheme", urlError(errors.New(`unsupported protocol scheme "ftp"`)), false},
+		{"host not found", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "mainnet.infura.io", IsNotFound: true}}), false},
+		{"dns timeout", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "mainnet.infura.io", IsTimeout: true}}), true},
+		{"canceled", urlError(context.Canceled), false},
+	}
+
+	for _, test := range tests {
+		if got := isRetryable(test.err); got != test.want {
+			t.Errorf("%s: isRetryable(%v) = %v, want %v", test.name, test.err, got, test.want)
+		}
+	}
+}
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Multiplier:     2,
+	}
+}
+
+// statusServer answers with the given statuses in turn, then with a
+// successful eth_blockNumber response.
+func statusServer(t *testing.T, attempts *int32, statuses ...int) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(attempts, 1)
+		if int(n) <= len(statuses) {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"message":"unavailable"}`, statuses[n-1])
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRetryAttempts(t *testing.T) {
+	var attempts int32
+	srv := statusServer(t, &attempts, 503, 502)
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(testRetryPolicy()))
+
+	n, err := g.GetBlockNumber(context.Background())
+	if err != nil || n != 16 {
+		t.Fatalf("GetBlockNumber = %d, %v", n, err)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var attempts int32
+	srv := statusServer(t, &attempts, 503, 503, 503, 503, 503)
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(testRetryPolicy()))
+
+	_, err := g.GetBlockNumber(context.Background())
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want HTTP 503", err)
+	}
+	if attempts != 4 {
+		t.Fatalf("attempts = %d, want 4", attempts)
+	}
+}
+
+func TestRetryPermanentErrors(t *testing.T) {
+	var attempts int32
+	srv := statusServer(t, &attempts, 401)
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(testRetryPolicy()))
+	if _, err := g.GetBlockNumber(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts on HTTP 401 = %d, want 1", attempts)
+	}
+
+	// the default client does not trust the certificate of the test server.
+	var tlsAttempts int32
+	tlsSrv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tlsAttempts, 1)
+	}))
+	tlsSrv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+	g = NewGinfura("mainnet", "", WithURL(tlsSrv.URL), WithRetryPolicy(RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Second,
+		Multiplier:     2,
+	}))
+
+	start := time.Now()
+	_, err := g.GetBlockNumber(context.Background())
+	var certErr x509.UnknownAuthorityError
+	if !errors.As(err, &certErr) {
+		t.Fatalf("err = %v, want an unknown authority error", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("TLS error was retried, took %v", elapsed)
+	}
+	if n := atomic.LoadInt32(&tlsAttempts); n != 0 {
+		t.Fatalf("server handled %d requests, want 0", n)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	var attempts int32
+	srv := statusServer(t, &attempts, 503)
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(testRetryPolicy()))
+	if _, err := g.SendRawTransaction(context.Background(), Bytes{0x01}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts of eth_sendRawTransaction on HTTP 503 = %d, want 1", attempts)
+	}
+
+	// a 429 guarantees the request was not processed.
+	attempts = 0
+	srv = statusServer(t, &attempts, 429)
+	g = NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(testRetryPolicy()))
+	g.SendRawTransaction(context.Background(), Bytes{0x01})
+	if attempts != 2 {
+		t.Fatalf("attempts of eth_sendRawTransaction on HTTP 429 = %d, want 2", attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer srv.Close()
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(testRetryPolicy()))
+
+	start := time.Now()
+	if _, err := g.GetBlockNumber(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		policy RetryPolicy
+		want   []time.Duration
+	}{
+		{
+			RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2},
+			[]time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second},
+		},
+		{
+			RetryPolicy{InitialBackoff: 100 * time.Millisecond},
+			[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 0.5},
+			[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+		},
+	}
+
+	for _, test := range tests {
+		for i, want := range test.want {
+			if got := test.policy.backoff(i + 1); got != want {
+				t.Errorf("%+v: backoff(%d) = %v, want %v", test.policy, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("parseRetryAfter(3) = %v", got)
+	}
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 8*time.Second || got > 10*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %v", date, got)
+	}
+	for _, value := range []string{"", "-1", "soon"} {
+		if got := parseRetryAfter(value); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %v, want 0", value, got)
+		}
+	}
+}
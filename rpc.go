@@ -0,0 +1,385 @@
+package ginfura
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const jsonrpcVersion = "2.0"
+
+// JSON-RPC error codes returned by Infura and Ethereum nodes.
+const (
+	ErrCodeExecutionReverted = 3
+	ErrCodeParseError        = -32700
+	ErrCodeInvalidRequest    = -32600
+	ErrCodeMethodNotFound    = -32601
+	ErrCodeInvalidParams     = -32602
+	ErrCodeInternalError     = -32603
+	ErrCodeServerError       = -32000
+	ErrCodeLimitExceeded     = -32005
+)
+
+// RPCError is the error object of a failed JSON-RPC call.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (err *RPCError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("json-rpc error %d", err.Code)
+	}
+	return err.Message
+}
+
+// IsExecutionReverted reports whether the call was reverted by the EVM.
+func (err *RPCError) IsExecutionReverted() bool {
+	return err.Code == ErrCodeExecutionReverted
+}
+
+// IsInvalidParams reports whether the node rejected the call arguments.
+func (err *RPCError) IsInvalidParams() bool {
+	return err.Code == ErrCodeInvalidParams
+}
+
+// IsLimitExceeded reports whether the call was refused by rate limiting.
+func (err *RPCError) IsLimitExceeded() bool {
+	return err.Code == ErrCodeLimitExceeded
+}
+
+// queryTooLargeMessages are the messages of errors returned when a query
+// spans too many results, or takes too long, to be answered at once. Errors
+// about an invalid range, such as fromBlock being after toBlock, must not
+// match.
+var queryTooLargeMessages = []string{
+	"query returned more than",
+	"query timeout exceeded",
+	"response size exceeded",
+	"block range is too large",
+	"block range too large",
+	"exceed maximum block range",
+}
+
+// IsQueryTooLarge reports whether the node refused the call because its
+// range or result set is too large, such as eth_getLogs returning more than
+// 10,000 results.
+func (err *RPCError) IsQueryTooLarge() bool {
+	message := strings.ToLower(err.Message)
+	for _, m := range queryTooLargeMessages {
+		if strings.Contains(message, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFilterNotFound reports whether the filter the call refers to does not
+// exist, usually because it expired after not being polled.
+func (err *RPCError) IsFilterNotFound() bool {
+	return strings.Contains(strings.ToLower(err.Message), "filter not found")
+}
+
+// HTTPError is returned when the endpoint answers with a non-2xx status.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+	// RetryAfter is the delay requested by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (err *HTTPError) Error() string {
+	if len(err.Body) == 0 {
+		return err.Status
+	}
+	return fmt.Sprintf("%s: %s", err.Status, bytes.TrimSpace(err.Body))
+}
+
+// Unwrap returns the JSON-RPC error carried by the response body, if any.
+func (err *HTTPError) Unwrap() error {
+	msg := jsonrpcMessage{}
+	if json.Unmarshal(err.Body, &msg) != nil || msg.Error == nil {
+		return nil
+	}
+	return msg.Error
+}
+
+// jsonrpcMessage is the envelope of a JSON-RPC request or response.
+type jsonrpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method,omitempty"`
+	Params  []interface{}   `json:"params"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// nextID returns a new, unique id for a JSON-RPC request.
+func (e *Ginfura) nextID() uint64 {
+	return atomic.AddUint64(&e.idCounter, 1)
+}
+
+// newMessage builds a JSON-RPC request for the given method and params.
+func (e *Ginfura) newMessage(method string, params ...interface{}) *jsonrpcMessage {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return &jsonrpcMessage{
+		JSONRPC: jsonrpcVersion,
+		ID:      e.nextID(),
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// CallContext performs a JSON-RPC call with the given method and params and
+// decodes the result into the value pointed to by result. If result is nil,
+// the response result is discarded. If the node answers with a JSON-RPC error
+// object, it is returned as an *RPCError.
+func (e *Ginfura) CallContext(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	msg := e.newMessage(method, params...)
+
+	key, err := callKey(method, msg.Params)
+	if err != nil {
+		return err
+	}
+
+	cacheable := e.cache != nil && cacheableMethods[method]
+	if cacheable {
+		if cached, ok := e.cache.Get(key); ok {
+			resp := &jsonrpcMessage{Result: cached}
+			return resp.decodeResult(result)
+		}
+	}
+
+	resp, err := e.coalescer.do(ctx, key, msg, func() (*jsonrpcMessage, error) {
+		resp := &jsonrpcMessage{}
+		return resp, e.sendHTTP(ctx, msg, resp)
+	})
+	if err != nil {
+		return err
+	}
+	if cacheable && resp.Error == nil && e.isFinal(ctx, method, resp.Result) {
+		e.cache.Set(key, resp.Result)
+	}
+	return resp.decodeResult(result)
+}
+
+// callKey identifies a call by its method and params.
+func callKey(method string, params []interface{}) (string, error) {
+	jsonValue, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return method + string(jsonValue), nil
+}
+
+// BatchElem is a single call of a batch request.
+type BatchElem struct {
+	Method string
+	Args   []interface{}
+	// Result is decoded into the value it points to. If nil, the result is
+	// discarded.
+	Result interface{}
+	// Error is set if the node returns an error for this call or the result
+	// cannot be decoded.
+	Error error
+}
+
+// BatchCallContext sends all calls of b in a single JSON-RPC batch request
+// and waits for the responses. The returned error only reports failures of
+// the request as a whole; errors of individual calls are set on their
+// BatchElem.
+func (e *Ginfura) BatchCallContext(ctx context.Context, b []BatchElem) error {
+	if len(b) == 0 {
+		return nil
+	}
+
+	msgs := make([]*jsonrpcMessage, len(b))
+	byID := make(map[uint64]int, len(b))
+	for i, elem := range b {
+		msgs[i] = e.newMessage(elem.Method, elem.Args...)
+		byID[msgs[i].ID] = i
+	}
+
+	resps := []*jsonrpcMessage{}
+	if err := e.sendHTTP(ctx, msgs, &resps); err != nil {
+		return err
+	}
+
+	for _, resp := range resps {
+		i, ok := byID[resp.ID]
+		if !ok {
+			continue
+		}
+		delete(byID, resp.ID)
+		b[i].Error = resp.decodeResult(b[i].Result)
+	}
+	for _, i := range byID {
+		b[i].Error = errMissingBatchResponse
+	}
+	return nil
+}
+
+// decodeResult returns the error of the response, or decodes its result
+// into the value pointed to by result.
+func (msg *jsonrpcMessage) decodeResult(result interface{}) error {
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if result == nil || len(msg.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(msg.Result, result)
+}
+
+// sendHTTP posts a JSON-RPC request, either a single message or a batch,
+// through the interceptor chain and decodes the response into resp, retrying
+// transient failures according to the retry policy. The JSON-RPC error of a
+// single message response is returned as an error.
+func (e *Ginfura) sendHTTP(ctx context.Context, req interface{}, resp interface{}) error {
+	call := newRPCCall(TransportHTTP, req)
+	return e.intercept(ctx, call, func(ctx context.Context, call *RPCCall) error {
+		return e.retryPolicy.withRetry(ctx, isIdempotent(req), func() error {
+			body, err := e.sendHTTPOnce(ctx, req, resp)
+			call.Response = body
+			if msg, ok := resp.(*jsonrpcMessage); ok && err == nil && msg.Error != nil {
+				return msg.Error
+			}
+			return err
+		})
+	})
+}
+
+// sendHTTPOnce performs a single attempt of sendHTTP and returns the raw
+// response. A node that answers a batch with a single error object has its
+// error returned.
+func (e *Ginfura) sendHTTPOnce(ctx context.Context, req interface{}, resp interface{}) ([]byte, error) {
+	if msg, ok := resp.(*jsonrpcMessage); ok {
+		*msg = jsonrpcMessage{}
+	}
+
+	if err := e.limiter.waitFor(ctx, req); err != nil {
+		return nil, err
+	}
+
+	body, err := e.postHTTP(ctx, req)
+	if err != nil {
+		return body, err
+	}
+
+	if err := json.Unmarshal(body, resp); err != nil {
+		errResp := jsonrpcMessage{}
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error != nil {
+			return body, errResp.Error
+		}
+		return body, err
+	}
+	return body, nil
+}
+
+// postHTTP posts the JSON encoding of req to the endpoint and returns the
+// response body. A non-2xx status is returned as an *HTTPError along with the
+// body. With failover endpoints configured, transient failures move on to the
+// next endpoint.
+func (e *Ginfura) postHTTP(ctx context.Context, req interface{}) ([]byte, error) {
+	jsonValue, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.failover == nil {
+		return e.postEndpoint(ctx, Endpoint{URL: e.url}, jsonValue)
+	}
+
+	idempotent := isIdempotent(req)
+	var (
+		lastBody []byte
+		lastErr  error
+	)
+	for _, endpoint := range e.failover.candidates() {
+		body, err := e.postEndpoint(ctx, endpoint, jsonValue)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil || !isRetryable(err) {
+			return body, err
+		}
+
+		e.failover.markFailed(endpoint.URL)
+		if !idempotent && !notProcessed(err) {
+			return body, err
+		}
+		lastBody, lastErr = body, err
+	}
+	return lastBody, lastErr
+}
+
+// callEndpoint calls method on endpoint only, bypassing the failover, retries
+// and interceptors of the client.
+func (e *Ginfura) callEndpoint(ctx context.Context, endpoint Endpoint, result interface{}, method string, params ...interface{}) error {
+	jsonValue, err := json.Marshal(e.newMessage(method, params...))
+	if err != nil {
+		return err
+	}
+	body, err := e.postEndpoint(ctx, endpoint, jsonValue)
+	if err != nil {
+		return err
+	}
+
+	resp := jsonrpcMessage{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	return resp.decodeResult(result)
+}
+
+// postEndpoint posts a JSON-encoded request to endpoint and returns the
+// response body, also when the status is not 2xx.
+func (e *Ginfura) postEndpoint(ctx context.Context, endpoint Endpoint, jsonValue []byte) ([]byte, error) {
+	if e.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.requestTimeout)
+		defer cancel()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewBuffer(jsonValue))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range e.header {
+		httpReq.Header[key] = values
+	}
+	for key, values := range endpoint.Header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return body, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       body,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return body, nil
+}
@@ -0,0 +1,120 @@
+package ginfura
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newBatchServer returns a test server answering batch requests with the
+// responses returned by answer.
+func newBatchServer(t *testing.T, answer func(reqs []jsonrpcMessage) interface{}) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []jsonrpcMessage
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(answer(reqs))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func rpcResult(id uint64, result interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": result}
+}
+
+func rpcError(id uint64, err *RPCError) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "error": err}
+}
+
+func TestBatchCallOutOfOrder(t *testing.T) {
+	srv := newBatchServer(t, func(reqs []jsonrpcMessage) interface{} {
+		var resps []interface{}
+		for i := len(reqs) - 1; i >= 0; i-- {
+			address := reqs[i].Params[0].(string)
+			resps = append(resps, rpcResult(reqs[i].ID, address[len(address)-1:]))
+		}
+		return resps
+	})
+	g := NewGinfura("mainnet", "", WithURL(srv.URL))
+
+	b := make([]BatchElem, 3)
+	results := make([]string, len(b))
+	for i := range b {
+		b[i] = BatchElem{
+			Method: "eth_getBalance",
+			Args:   []interface{}{Address{19: byte(i + 1)}, "latest"},
+			Result: &results[i],
+		}
+	}
+	if err := g.BatchCallContext(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+	for i, elem := range b {
+		if elem.Error != nil {
+			t.Fatalf("call #%d: %v", i, elem.Error)
+		}
+		if want := string(rune('1' + i)); results[i] != want {
+			t.Fatalf("call #%d got %q, want %q", i, results[i], want)
+		}
+	}
+}
+
+func TestBatchCallErrors(t *testing.T) {
+	srv := newBatchServer(t, func(reqs []jsonrpcMessage) interface{} {
+		// the second call fails and the third has no response.
+		return []interface{}{
+			rpcResult(reqs[0].ID, "0x10"),
+			rpcError(reqs[1].ID, &RPCError{Code: ErrCodeExecutionReverted, Message: "execution reverted"}),
+			rpcResult(12345, "0x1"),
+		}
+	})
+	g := NewGinfura("mainnet", "", WithURL(srv.URL))
+
+	var number, call, balance string
+	b := []BatchElem{
+		{Method: "eth_blockNumber", Result: &number},
+		{Method: "eth_call", Args: []interface{}{map[string]string{}, "latest"}, Result: &call},
+		{Method: "eth_getBalance", Args: []interface{}{Address{}, "latest"}, Result: &balance},
+	}
+	if err := g.BatchCallContext(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+
+	if b[0].Error != nil || number != "0x10" {
+		t.Fatalf("call #0 = %q, %v", number, b[0].Error)
+	}
+	if rpcErr, ok := b[1].Error.(*RPCError); !ok || !rpcErr.IsExecutionReverted() {
+		t.Fatalf("call #1 error = %v, want execution reverted", b[1].Error)
+	}
+	if b[2].Error != errMissingBatchResponse {
+		t.Fatalf("call #2 error = %v, want %v", b[2].Error, errMissingBatchResponse)
+	}
+}
+
+func TestBatchCallSingleError(t *testing.T) {
+	srv := newBatchServer(t, func(reqs []jsonrpcMessage) interface{} {
+		return rpcError(0, &RPCError{Code: ErrCodeInvalidRequest, Message: "batch size too large"})
+	})
+	g := NewGinfura("mainnet", "", WithURL(srv.URL), WithRetryPolicy(RetryPolicy{}))
+
+	b := []BatchElem{{Method: "eth_blockNumber"}, {Method: "eth_gasPrice"}}
+	err := g.BatchCallContext(context.Background(), b)
+	rpcErr, ok := err.(*RPCError)
+	if !ok || rpcErr.Code != ErrCodeInvalidRequest {
+		t.Fatalf("err = %v, want the error of the batch", err)
+	}
+}
+
+func TestBatchCallEmpty(t *testing.T) {
+	g := NewGinfura("mainnet", "", WithURL("http://127.0.0.1:0"))
+	if err := g.BatchCallContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package ginfura
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// span attribute keys
+const (
+	AttrMethod       = "rpc.method"
+	AttrTransport    = "rpc.transport"
+	AttrNetwork      = "ginfura.network"
+	AttrBatchSize    = "rpc.batch_size"
+	AttrSubscription = "rpc.subscription"
+	AttrResponseSize = "rpc.response_size"
+	AttrErrorCode    = "rpc.error_code"
+)
+
+// Tracer starts spans for JSON-RPC requests. See the otelginfura package for
+// an OpenTelemetry implementation.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a unit of work started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// tracingInterceptor wraps every request in a span of tracer.
+func tracingInterceptor(tracer Tracer, network string) Interceptor {
+	return func(ctx context.Context, call *RPCCall, next Invoker) error {
+		name := call.Method
+		if len(call.Batch) > 0 {
+			name = "batch"
+		}
+
+		ctx, span := tracer.Start(ctx, name)
+		defer span.End()
+
+		span.SetAttribute(AttrTransport, call.Transport)
+		if network != "" {
+			span.SetAttribute(AttrNetwork, network)
+		}
+		if len(call.Batch) > 0 {
+			span.SetAttribute(AttrBatchSize, len(call.Batch))
+		} else {
+			span.SetAttribute(AttrMethod, call.Method)
+		}
+		if call.Method == "eth_subscribe" && len(call.Params) > 0 {
+			span.SetAttribute(AttrSubscription, call.Params[0])
+		}
+
+		err := next(ctx, call)
+		span.SetAttribute(AttrResponseSize, len(call.Response))
+		if err != nil {
+			span.SetAttribute(AttrErrorCode, errorCode(err))
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+// SpanRecorder is an in-memory Tracer that keeps every finished span, meant
+// for tests.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan is a span kept by a SpanRecorder.
+type RecordedSpan struct {
+	Name       string
+	Attributes map[string]interface{}
+	Err        error
+	Start      time.Time
+	End        time.Time
+
+	recorder *SpanRecorder
+}
+
+// NewSpanRecorder returns an empty span recorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Start implements Tracer.
+func (r *SpanRecorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, &recordingSpan{
+		span: &RecordedSpan{
+			Name:       name,
+			Attributes: make(map[string]interface{}),
+			Start:      time.Now(),
+			recorder:   r,
+		},
+	}
+}
+
+// Spans returns the finished spans in the order they ended.
+func (r *SpanRecorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spans := make([]*RecordedSpan, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// Reset drops all recorded spans.
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	r.spans = nil
+	r.mu.Unlock()
+}
+
+// recordingSpan is the Span handed out by a SpanRecorder. It is only visible
+// to the recorder once ended.
+type recordingSpan struct {
+	span *RecordedSpan
+	once sync.Once
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.span.Attributes[key] = value
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.span.Err = err
+}
+
+func (s *recordingSpan) End() {
+	s.once.Do(func() {
+		s.span.End = time.Now()
+
+		r := s.span.recorder
+		r.mu.Lock()
+		r.spans = append(r.spans, s.span)
+		r.mu.Unlock()
+	})
+}
@@ -0,0 +1,112 @@
+package ginfura
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// TxType is the EIP-2718 type of a transaction.
+type TxType uint8
+
+// transaction types
+const (
+	LegacyTxType     TxType = 0x00
+	AccessListTxType TxType = 0x01 // EIP-2930
+	DynamicFeeTxType TxType = 0x02 // EIP-1559
+	BlobTxType       TxType = 0x03 // EIP-4844
+	SetCodeTxType    TxType = 0x04 // EIP-7702
+)
+
+func (t TxType) String() string {
+	switch t {
+	case LegacyTxType:
+		return "legacy"
+	case AccessListTxType:
+		return "access list"
+	case DynamicFeeTxType:
+		return "dynamic fee"
+	case BlobTxType:
+		return "blob"
+	case SetCodeTxType:
+		return "set code"
+	}
+	return "unknown type " + strconv.Itoa(int(t))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t TxType) MarshalText() ([]byte, error) {
+	return Quantity(t).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *TxType) UnmarshalText(input []byte) error {
+	var q Quantity
+	if err := q.UnmarshalText(input); err != nil {
+		return err
+	}
+	if q > 0xff {
+		return errInvalidTxType
+	}
+	*t = TxType(q)
+	return nil
+}
+
+// AccessList is an EIP-2930 list of the addresses and storage keys a
+// transaction plans to access.
+type AccessList []AccessTuple
+
+// AccessTuple is an entry of an access list.
+type AccessTuple struct {
+	Address     Address `json:"address"`
+	StorageKeys []Hash  `json:"storageKeys"`
+}
+
+// SetCodeAuthorization is an EIP-7702 authorization to set the code of the
+// signing account to a delegation to Address.
+type SetCodeAuthorization struct {
+	ChainID *big.Int // zero for any chain
+	Address Address
+	Nonce   Quantity
+	YParity Quantity
+	R       *big.Int
+	S       *big.Int
+}
+
+// HasDynamicFee reports whether tx prices its gas with a fee cap and a
+// priority fee rather than a fixed gas price.
+func (tx Transaction) HasDynamicFee() bool {
+	switch tx.Type {
+	case DynamicFeeTxType, BlobTxType, SetCodeTxType:
+		return true
+	}
+	return false
+}
+
+// EffectiveGasPrice returns the price per gas tx pays in a block with the
+// given base fee. For dynamic fee transactions this is the base fee plus the
+// priority fee, capped at the fee cap. If baseFee is nil, the gas price
+// reported by the node is returned.
+func (tx Transaction) EffectiveGasPrice(baseFee *big.Int) *big.Int {
+	if !tx.HasDynamicFee() || baseFee == nil || tx.MaxFeePerGas == nil || tx.MaxPriorityFeePerGas == nil {
+		if tx.GasPrice == nil {
+			return nil
+		}
+		return new(big.Int).Set(tx.GasPrice)
+	}
+
+	price := new(big.Int).Add(baseFee, tx.MaxPriorityFeePerGas)
+	if price.Cmp(tx.MaxFeePerGas) > 0 {
+		price.Set(tx.MaxFeePerGas)
+	}
+	return price
+}
+
+// EffectivePriorityFee returns the price per gas paid to the block producer
+// in a block with the given base fee, or nil if baseFee is nil.
+func (tx Transaction) EffectivePriorityFee(baseFee *big.Int) *big.Int {
+	price := tx.EffectiveGasPrice(baseFee)
+	if price == nil || baseFee == nil {
+		return nil
+	}
+	return price.Sub(price, baseFee)
+}
@@ -0,0 +1,133 @@
+package ginfura
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1000000000))
+}
+
+func TestEffectiveGasPrice(t *testing.T) {
+	legacy := Transaction{Type: LegacyTxType, GasPrice: gwei(20)}
+	accessList := Transaction{Type: AccessListTxType, GasPrice: gwei(15)}
+	dynamic := func(txType TxType) Transaction {
+		// nodes report the fee cap as the gas price of pending transactions.
+		return Transaction{Type: txType, GasPrice: gwei(30), MaxFeePerGas: gwei(30), MaxPriorityFeePerGas: gwei(2)}
+	}
+
+	tests := []struct {
+		tx      Transaction
+		baseFee *big.Int
+		want    *big.Int
+	}{
+		{legacy, gwei(10), gwei(20)},
+		{legacy, nil, gwei(20)},
+		{accessList, gwei(10), gwei(15)},
+		{dynamic(DynamicFeeTxType), gwei(10), gwei(12)},
+		{dynamic(DynamicFeeTxType), gwei(29), gwei(30)},
+		{dynamic(DynamicFeeTxType), nil, gwei(30)},
+		{dynamic(BlobTxType), gwei(10), gwei(12)},
+		{dynamic(SetCodeTxType), gwei(10), gwei(12)},
+		{dynamic(SetCodeTxType), gwei(40), gwei(30)},
+		{Transaction{Type: LegacyTxType}, gwei(10), nil},
+	}
+
+	for _, test := range tests {
+		got := test.tx.EffectiveGasPrice(test.baseFee)
+		if (got == nil) != (test.want == nil) || got != nil && got.Cmp(test.want) != 0 {
+			t.Errorf("%s transaction with base fee %v: effective gas price = %v, want %v", test.tx.Type, test.baseFee, got, test.want)
+		}
+	}
+
+	tx := dynamic(SetCodeTxType)
+	if fee := tx.EffectivePriorityFee(gwei(10)); fee.Cmp(gwei(2)) != 0 {
+		t.Errorf("effective priority fee = %v, want %v", fee, gwei(2))
+	}
+	if tx.GasPrice.Cmp(gwei(30)) != 0 {
+		t.Error("EffectiveGasPrice modified the transaction")
+	}
+}
+
+func TestTxTypeString(t *testing.T) {
+	tests := map[TxType]string{
+		LegacyTxType:     "legacy",
+		AccessListTxType: "access list",
+		DynamicFeeTxType: "dynamic fee",
+		BlobTxType:       "blob",
+		SetCodeTxType:    "set code",
+		0x05:             "unknown type 5",
+	}
+	for txType, want := range tests {
+		if got := txType.String(); got != want {
+			t.Errorf("TxType(%d).String() = %q, want %q", uint8(txType), got, want)
+		}
+	}
+}
+
+func TestDecodeSetCodeTransaction(t *testing.T) {
+	input := `{
+		"type": "0x4",
+		"chainId": "0x1",
+		"nonce": "0x7",
+		"gas": "0x186a0",
+		"maxFeePerGas": "0x77359400",
+		"maxPriorityFeePerGas": "0x3b9aca00",
+		"to": "0x63c0c19a282a1b52b07dd5a65b58948a07dae32b",
+		"value": "0x0",
+		"input": "0x",
+		"accessList": [],
+		"authorizationList": [{
+			"chainId": "0x0",
+			"address": "0x63c0c19a282a1b52b07dd5a65b58948a07dae32b",
+			"nonce": "0x8",
+			"yParity": "0x1",
+			"r": "0x3a4e2a5b1e2c1f7c7f3c0b8f0e6a1f2d4c5b6a79887766554433221100ffeedd",
+			"s": "0x1c2b3a4958677685940a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60"
+		}],
+		"v": "0x0",
+		"yParity": "0x0",
+		"r": "0x1",
+		"s": "0x2",
+		"hash": "0x5e3c0f3f2b0e8a7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a3928170605",
+		"from": "0x8ba1f109551bd432803012645ac136ddd64dba72",
+		"gasPrice": "0x77359400",
+		"blockHash": null,
+		"blockNumber": null,
+		"transactionIndex": null
+	}`
+
+	var tx Transaction
+	if err := json.Unmarshal([]byte(input), &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type != SetCodeTxType || !tx.HasDynamicFee() {
+		t.Fatalf("type = %v", tx.Type)
+	}
+	if len(tx.AuthorizationList) != 1 {
+		t.Fatalf("authorization list = %+v", tx.AuthorizationList)
+	}
+	auth := tx.AuthorizationList[0]
+	if auth.ChainID == nil || auth.ChainID.Sign() != 0 || auth.Nonce != 8 || auth.YParity != 1 || auth.Address != *tx.To || auth.R == nil || auth.S == nil {
+		t.Fatalf("authorization = %+v", auth)
+	}
+
+	// a pending set code transaction pays at most its fee cap.
+	if price := tx.EffectiveGasPrice(big.NewInt(1500000000)); price.Int64() != 2000000000 {
+		t.Fatalf("effective gas price = %v", price)
+	}
+
+	output, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Transaction
+	if err := json.Unmarshal(output, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.AuthorizationList) != 1 || decoded.AuthorizationList[0].R.Cmp(auth.R) != 0 || decoded.AuthorizationList[0].ChainID.Sign() != 0 {
+		t.Fatalf("round trip of authorization = %+v", decoded.AuthorizationList)
+	}
+}
@@ -1,17 +1,37 @@
 package ginfura
 
 import (
+	"encoding/json"
+	"math/big"
+
 	"github.com/pkg/errors"
 )
 
 // common errors
 var (
 	errNotEthereumAddress             = errors.New("input is not an ethereum address")
+	errNotHash                        = errors.New("input is not a 32 bytes hash")
+	errInvalidTxType                  = errors.New("transaction type should fit in a byte")
+	errBlockHashWithRange             = errors.New("block hash cannot be combined with a block range")
+	errInvalidBlockNumber             = errors.New("block number should be a number or `latest`, `pending`, `earliest`, `safe`, `finalized`")
 	errNotOpenWebsocketConnection     = errors.New("websocket connection is not yet opened")
 	errNotSubscribePendingTransaction = errors.New("pending transactions is not yet subscribed")
 	errNotSubscribeNewHeads           = errors.New("new heads is not yet subscribed")
 	errNotSubscribeLogs               = errors.New("logs event is not yet subscribed")
 	errAlreadySubscribe               = errors.New("already subscribe the topic")
+	errMissingBatchResponse           = errors.New("response batch did not contain a response to this call")
+	errNoEndpoints                    = errors.New("at least one endpoint is required")
+	errUnknownNetwork                 = errors.New("unknown network, set the expected chain id with WithChainID")
+)
+
+// ErrNotFound is returned when a block, uncle, transaction or receipt lookup
+// yields no result.
+var ErrNotFound = errors.New("not found")
+
+// rate limiting errors
+var (
+	ErrRateLimited      = errors.New("request rate limit reached")
+	ErrCreditsExhausted = errors.New("daily credit budget exhausted")
 )
 
 // subscription types
@@ -22,209 +42,253 @@ const (
 	Syncing               = "syncing"
 )
 
+// receipt statuses
+const (
+	ReceiptStatusFailed     Quantity = 0
+	ReceiptStatusSuccessful Quantity = 1
+)
+
 // Block type of a block in ethereum blockchain
 type Block struct {
-	Difficulty       string   `json:"difficulty"`
-	ExtraData        string   `json:"extraData"`
-	GasLimit         string   `json:"gasLimit"`
-	GasUsed          string   `json:"gasUsed"`
-	Hash             string   `json:"hash"`
-	LogsBloom        string   `json:"logsBloom"`
-	Miner            string   `json:"miner"`
-	MixHash          string   `json:"mixHash"`
-	Nonce            string   `json:"nonce"`
-	Number           string   `json:"number"`
-	ParentHash       string   `json:"parentHash"`
-	ReceiptsRoot     string   `json:"receiptsRoot"`
-	Sha3Uncles       string   `json:"sha3Uncles"`
-	Size             string   `json:"size"`
-	StateRoot        string   `json:"stateRoot"`
-	Timestamp        string   `json:"timestamp"`
-	TotalDifficulty  string   `json:"totalDifficulty"`
-	Transactions     []string `json:"transactions"`
-	TransactionsRoot string   `json:"transactionsRoot"`
-	Uncles           []string `json:"uncles"`
+	Difficulty       *big.Int `json:"difficulty"`
+	ExtraData        Bytes    `json:"extraData"`
+	GasLimit         Quantity `json:"gasLimit"`
+	GasUsed          Quantity `json:"gasUsed"`
+	Hash             Hash     `json:"hash"`
+	LogsBloom        Bytes    `json:"logsBloom"`
+	Miner            Address  `json:"miner"`
+	MixHash          Hash     `json:"mixHash"`
+	Nonce            Bytes    `json:"nonce"`
+	Number           Quantity `json:"number"`
+	ParentHash       Hash     `json:"parentHash"`
+	ReceiptsRoot     Hash     `json:"receiptsRoot"`
+	Sha3Uncles       Hash     `json:"sha3Uncles"`
+	Size             Quantity `json:"size"`
+	StateRoot        Hash     `json:"stateRoot"`
+	Timestamp        Quantity `json:"timestamp"`
+	TotalDifficulty  *big.Int `json:"totalDifficulty"`
+	Transactions     []Hash   `json:"transactions"` // also filled when full transactions are returned
+	TransactionsRoot Hash     `json:"transactionsRoot"`
+	Uncles           []Hash   `json:"uncles"`
+
+	// London
+	BaseFeePerGas *big.Int `json:"baseFeePerGas"`
+
+	// Shanghai
+	Withdrawals     []Withdrawal `json:"withdrawals,omitempty"`
+	WithdrawalsRoot *Hash        `json:"withdrawalsRoot,omitempty"`
+
+	// Cancun
+	BlobGasUsed           *Quantity `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas         *Quantity `json:"excessBlobGas,omitempty"`
+	ParentBeaconBlockRoot *Hash     `json:"parentBeaconBlockRoot,omitempty"`
+
+	// Prague
+	RequestsHash *Hash `json:"requestsHash,omitempty"`
+}
+
+// Withdrawal is a validator withdrawal included in a block since Shanghai.
+type Withdrawal struct {
+	Index          Quantity `json:"index"`
+	ValidatorIndex Quantity `json:"validatorIndex"`
+	Address        Address  `json:"address"`
+	Amount         Quantity `json:"amount"` // in Gwei
+}
+
+// BlockWithTransactions is a block whose transactions are returned as full
+// objects instead of hashes.
+type BlockWithTransactions struct {
+	Block
+	Transactions []Transaction `json:"transactions"`
 }
 
 // Transaction ...
 type Transaction struct {
-	Hash             string `json:"hash"`
-	Nonce            string `json:"nonce"`
-	BlockHash        string `json:"blockHash"`
-	BlockNumber      string `json:"blockNumber"`
-	TransactionIndex string `json:"transactionIndex"`
-	From             string `json:"from"`
-	To               string `json:"to"`
-	Value            string `json:"value"`
-	GasPrice         string `json:"gasPrice"`
-	Gas              string `json:"gas"`
-	Input            string `json:"input"`
+	Type             TxType    `json:"type"`
+	Hash             Hash      `json:"hash"`
+	Nonce            Quantity  `json:"nonce"`
+	BlockHash        *Hash     `json:"blockHash"`        // nil while pending
+	BlockNumber      *Quantity `json:"blockNumber"`      // nil while pending
+	TransactionIndex *Quantity `json:"transactionIndex"` // nil while pending
+	From             Address   `json:"from"`
+	To               *Address  `json:"to"` // nil for contract creations
+	Value            *big.Int  `json:"value"`
+	GasPrice         *big.Int  `json:"gasPrice"`
+	Gas              Quantity  `json:"gas"`
+	Input            Bytes     `json:"input"`
+	ChainID          *big.Int  `json:"chainId"` // nil for legacy transactions without replay protection
+
+	// EIP-2930
+	AccessList AccessList `json:"accessList,omitempty"`
+
+	// EIP-1559
+	MaxFeePerGas         *big.Int `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *big.Int `json:"maxPriorityFeePerGas"`
+
+	// EIP-4844
+	MaxFeePerBlobGas    *big.Int `json:"maxFeePerBlobGas"`
+	BlobVersionedHashes []Hash   `json:"blobVersionedHashes,omitempty"`
+
+	// EIP-7702
+	AuthorizationList []SetCodeAuthorization `json:"authorizationList,omitempty"`
+
+	// signature
+	V       *big.Int  `json:"v"`
+	R       *big.Int  `json:"r"`
+	S       *big.Int  `json:"s"`
+	YParity *Quantity `json:"yParity,omitempty"` // nil for legacy transactions
 }
 
 // TransactionCall ...
 type TransactionCall struct {
-	From     string `json:"from,omitempty"`
-	To       string `json:"to"`
-	Gas      string `json:"gas,omitempty"`
-	GasPrice string `json:"gasPrice,omitempty"`
-	Value    string `json:"value,omitempty"`
-	Data     string `json:"data,omitempty"`
+	From     *Address  `json:"from,omitempty"`
+	To       *Address  `json:"to"`
+	Gas      *Quantity `json:"gas,omitempty"`
+	GasPrice *big.Int  `json:"gasPrice,omitempty"`
+	Value    *big.Int  `json:"value,omitempty"`
+	Data     Bytes     `json:"data,omitempty"`
 }
 
 // TransactionReceipt ...
 type TransactionReceipt struct {
-	TransactionHash   string `json:"transactionHash"`
-	TransactionIndex  string `json:"transactionIndex"`
-	BlockHash         string `json:"blockHash"`
-	BlockNumber       string `json:"blockNumber"`
-	From              string `json:"from"`
-	To                string `json:"to"`
-	CumulativeGasUsed string `json:"cumulativeGasUsed"`
-	GasUsed           string `json:"gasUsed"`
-	ContractAddress   string `json:"contractAddress"`
-	Logs              []Log  `json:"logs"`
-	LogsBloom         string `json:"logsBloom"`
+	Type              TxType    `json:"type"`
+	TransactionHash   Hash      `json:"transactionHash"`
+	TransactionIndex  Quantity  `json:"transactionIndex"`
+	BlockHash         Hash      `json:"blockHash"`
+	BlockNumber       Quantity  `json:"blockNumber"`
+	From              Address   `json:"from"`
+	To                *Address  `json:"to"` // nil for contract creations
+	CumulativeGasUsed Quantity  `json:"cumulativeGasUsed"`
+	GasUsed           Quantity  `json:"gasUsed"`
+	EffectiveGasPrice *big.Int  `json:"effectiveGasPrice"`
+	ContractAddress   *Address  `json:"contractAddress"` // nil unless a contract was created
+	Logs              []Log     `json:"logs"`
+	LogsBloom         Bytes     `json:"logsBloom"`
+	Status            *Quantity `json:"status,omitempty"` // nil before Byzantium
+	Root              *Hash     `json:"root,omitempty"`   // post-transaction state root before Byzantium
+
+	// EIP-4844
+	BlobGasUsed  *Quantity `json:"blobGasUsed,omitempty"`
+	BlobGasPrice *big.Int  `json:"blobGasPrice"`
+}
+
+// Failed reports whether the transaction was reverted. Receipts from before
+// Byzantium carry no status and are never reported as failed.
+func (r TransactionReceipt) Failed() bool {
+	return r.Status != nil && *r.Status == ReceiptStatusFailed
 }
 
 // Log ...
 type Log struct {
-	Address          string   `json:"address"`
-	BlockHash        string   `json:"blockHash"`
-	BlockNumber      string   `json:"blockNumber"`
-	Data             string   `json:"data"`
-	LogIndex         string   `json:"logIndex"`
+	Address          Address  `json:"address"`
+	BlockHash        Hash     `json:"blockHash"`
+	BlockNumber      Quantity `json:"blockNumber"`
+	Data             Bytes    `json:"data"`
+	LogIndex         Quantity `json:"logIndex"`
 	Removed          bool     `json:"removed"`
-	Topics           []string `json:"topics"`
-	TransactionHash  string   `json:"transactionHash"`
-	TransactionIndex string   `json:"transactionIndex"`
+	Topics           []Hash   `json:"topics"`
+	TransactionHash  Hash     `json:"transactionHash"`
+	TransactionIndex Quantity `json:"transactionIndex"`
 }
 
 // UncleBlock ...
 type UncleBlock struct {
-	Number           string   `json:"number"`
-	Hash             string   `json:"hash"`
-	ParentHash       string   `json:"parentHash"`
-	Nonce            string   `json:"nonce"`
-	Sha3Uncles       string   `json:"sha3Uncles"`
-	LogsBloom        string   `json:"logsBloom"`
-	TransactionsRoot string   `json:"transactionsRoot"`
-	StateRoot        string   `json:"stateRoot"`
-	ReceiptsRoot     string   `json:"receiptsRoot"`
-	Miner            string   `json:"miner"`
-	Difficulty       string   `json:"difficulty"`
-	TotalDifficulty  string   `json:"totalDifficulty"`
-	ExtraData        string   `json:"extraData"`
-	Size             string   `json:"size"`
-	GasLimit         string   `json:"gasLimit"`
-	GasUsed          string   `json:"gasUsed"`
-	Timestamp        string   `json:"timestamp"`
-	Uncles           []string `json:"uncles"`
-}
-
-type blkInfoResp struct {
-	JSONRPC string `json:"jsonrpc"`
-	ID      int    `json:"id"`
-	Result  Block  `json:"result"`
+	Number           Quantity `json:"number"`
+	Hash             Hash     `json:"hash"`
+	ParentHash       Hash     `json:"parentHash"`
+	Nonce            Bytes    `json:"nonce"`
+	Sha3Uncles       Hash     `json:"sha3Uncles"`
+	LogsBloom        Bytes    `json:"logsBloom"`
+	TransactionsRoot Hash     `json:"transactionsRoot"`
+	StateRoot        Hash     `json:"stateRoot"`
+	ReceiptsRoot     Hash     `json:"receiptsRoot"`
+	Miner            Address  `json:"miner"`
+	Difficulty       *big.Int `json:"difficulty"`
+	TotalDifficulty  *big.Int `json:"totalDifficulty"`
+	ExtraData        Bytes    `json:"extraData"`
+	Size             Quantity `json:"size"`
+	GasLimit         Quantity `json:"gasLimit"`
+	GasUsed          Quantity `json:"gasUsed"`
+	Timestamp        Quantity `json:"timestamp"`
+	Uncles           []Hash   `json:"uncles"`
 }
 
-type uncleBlkResp struct {
-	JSONRPC string     `json:"jsonrpc"`
-	ID      int        `json:"id"`
-	Result  UncleBlock `json:"result"`
-}
+// Header is the header of a new block delivered by SubscribeNewHead.
+type Header struct {
+	Difficulty       *big.Int `json:"difficulty"`
+	ExtraData        Bytes    `json:"extraData"`
+	GasLimit         Quantity `json:"gasLimit"`
+	GasUsed          Quantity `json:"gasUsed"`
+	Hash             Hash     `json:"hash"`
+	LogsBloom        Bytes    `json:"logsBloom"`
+	Miner            Address  `json:"miner"`
+	MixHash          Hash     `json:"mixHash"`
+	Nonce            Bytes    `json:"nonce"`
+	Number           Quantity `json:"number"`
+	ParentHash       Hash     `json:"parentHash"`
+	ReceiptsRoot     Hash     `json:"receiptsRoot"`
+	Sha3Uncles       Hash     `json:"sha3Uncles"`
+	StateRoot        Hash     `json:"stateRoot"`
+	Timestamp        Quantity `json:"timestamp"`
+	TransactionsRoot Hash     `json:"transactionsRoot"`
 
-type txInfoResp struct {
-	JSONRPC string      `json:"jsonrpc"`
-	ID      int         `json:"id"`
-	Result  Transaction `json:"result"`
+	BaseFeePerGas         *big.Int  `json:"baseFeePerGas"` // nil before London
+	WithdrawalsRoot       *Hash     `json:"withdrawalsRoot,omitempty"`
+	BlobGasUsed           *Quantity `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas         *Quantity `json:"excessBlobGas,omitempty"`
+	ParentBeaconBlockRoot *Hash     `json:"parentBeaconBlockRoot,omitempty"`
+	RequestsHash          *Hash     `json:"requestsHash,omitempty"` // nil before Prague
 }
 
-type txReceiptResp struct {
-	JSONRPC string             `json:"jsonrpc"`
-	ID      int                `json:"id"`
-	Result  TransactionReceipt `json:"result"`
-}
-
-type response struct {
-	Jsonrpc string `json:"jsonrpc"`
-	ID      int    `json:"id"`
-	Result  string `json:"result"`
+// Header returns the header of b.
+func (b Block) Header() Header {
+	return Header{
+		Difficulty:            b.Difficulty,
+		ExtraData:             b.ExtraData,
+		GasLimit:              b.GasLimit,
+		GasUsed:               b.GasUsed,
+		Hash:                  b.Hash,
+		LogsBloom:             b.LogsBloom,
+		Miner:                 b.Miner,
+		MixHash:               b.MixHash,
+		Nonce:                 b.Nonce,
+		Number:                b.Number,
+		ParentHash:            b.ParentHash,
+		ReceiptsRoot:          b.ReceiptsRoot,
+		Sha3Uncles:            b.Sha3Uncles,
+		StateRoot:             b.StateRoot,
+		Timestamp:             b.Timestamp,
+		TransactionsRoot:      b.TransactionsRoot,
+		BaseFeePerGas:         b.BaseFeePerGas,
+		WithdrawalsRoot:       b.WithdrawalsRoot,
+		BlobGasUsed:           b.BlobGasUsed,
+		ExcessBlobGas:         b.ExcessBlobGas,
+		ParentBeaconBlockRoot: b.ParentBeaconBlockRoot,
+		RequestsHash:          b.RequestsHash,
+	}
 }
 
 //////////////// Websocket //////////////////
-type txPendingParams struct {
-	Subscription string `json:"subscription"`
-	Result       string `json:"result"`
-}
-
-type txPendingResp struct {
-	JSONRPC string          `json:"jsonrpc"`
-	Method  string          `json:"method"`
-	Params  txPendingParams `json:"params"`
-}
 
-type unsubscribeResp struct {
-	ID      int    `json:"id"`
-	JSONRPC string `json":"jsonrpc"`
-	Result  bool   `json:"result"`
-}
-
-type subscriptionResp struct {
-	ID      int    `json:"id"`
-	JSONRPC string `json:"jsonrpc"`
-	Result  string `json:"result"`
-}
-
-type newHeadResult struct {
-	Difficulty       string `json:"difficulty"`
-	ExtraData        string `json:"extraData"`
-	GasLimit         string `json:"gasLimit"`
-	GasUsed          string `json:"gasUsed"`
-	LogsBloom        string `json:"logsBloom"`
-	Miner            string `json:"miner"`
-	Nonce            string `json:"nonce"`
-	Number           string `json:"number"`
-	ParentHash       string `json:"parentHash"`
-	ReceiptRoot      string `json:"receiptRoot"`
-	Sha3Uncles       string `json:"sha3Uncles"`
-	StateRoot        string `json:"stateRoot"`
-	Timestamp        string `json:"timestamp"`
-	TransactionsRoot string `json:"transactionsRoot"`
-}
-type newHeadParams struct {
-	Result       newHeadResult `json:"result"`
-	Subscription string        `json:"subscription"`
-}
-
-type newHeadsResp struct {
-	JSONRPC string        `json:"jsonrpc"`
-	Method  string        `json:"method"`
-	Params  newHeadParams `json:"params"`
-}
-
-type logsResult struct {
-	Address          string   `json:"address"`
-	BlockHash        string   `json:"blockHash"`
-	BlockNumber      string   `json:"blockNumber"`
-	Data             string   `json:"data"`
-	LogIndex         string   `json:"logIndex"`
-	Topics           []string `json:"topics"`
-	TransactionHash  string   `json:"transactionHash"`
-	TransactionIndex string   `json:"transactionIndex"`
-}
+// wsMessage is a message received over a websocket connection, either a
+// response to a request or a subscription notification.
+type wsMessage struct {
+	JSONRPC string             `json:"jsonrpc"`
+	ID      uint64             `json:"id"`
+	Method  string             `json:"method"`
+	Params  notificationParams `json:"params"`
+	Result  json.RawMessage    `json:"result"`
+	Error   *RPCError          `json:"error"`
 
-type logsParams struct {
-	Subscription string     `json:"subscription"`
-	Result       logsResult `json:"result"`
+	raw []byte
 }
 
-type logsResp struct {
-	JSONRPC string     `json:"jsonrpc"`
-	Method  string     `json:"method"`
-	Params  logsParams `json:"params"`
+type notificationParams struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
 }
 
 type LogRequestParams struct {
-	Address []string `json:"address"`
-	Topics  []string `json:"topics"`
+	Address []Address `json:"address"`
+	Topics  []Hash    `json:"topics"`
 }
@@ -0,0 +1,344 @@
+package ginfura
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+// hexBig is the JSON encoding of a quantity as a hex string.
+type hexBig big.Int
+
+func (b *hexBig) UnmarshalJSON(input []byte) error {
+	var str string
+	if err := json.Unmarshal(input, &str); err != nil {
+		return err
+	}
+	v, err := parseBig(str)
+	if err != nil {
+		return err
+	}
+	*b = hexBig(*v)
+	return nil
+}
+
+func (b *hexBig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeBig((*big.Int)(b)))
+}
+
+func (b *hexBig) toInt() *big.Int {
+	return (*big.Int)(b)
+}
+
+// txHashOrObject decodes a block transaction given either as a hash or as a
+// full transaction object, keeping only the hash.
+type txHashOrObject Hash
+
+func (h *txHashOrObject) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '{' {
+		var tx struct {
+			Hash Hash `json:"hash"`
+		}
+		if err := json.Unmarshal(input, &tx); err != nil {
+			return err
+		}
+		*h = txHashOrObject(tx.Hash)
+		return nil
+	}
+	return json.Unmarshal(input, (*Hash)(h))
+}
+
+// UnmarshalJSON decodes the hex-encoded quantities of a block. Transactions
+// holds the transaction hashes whether the block was requested with full
+// transaction objects or not.
+func (b *Block) UnmarshalJSON(input []byte) error {
+	type block Block
+	dec := struct {
+		*block
+		Difficulty      *hexBig          `json:"difficulty"`
+		TotalDifficulty *hexBig          `json:"totalDifficulty"`
+		BaseFeePerGas   *hexBig          `json:"baseFeePerGas"`
+		Transactions    []txHashOrObject `json:"transactions"`
+	}{block: (*block)(b)}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	b.Difficulty = dec.Difficulty.toInt()
+	b.TotalDifficulty = dec.TotalDifficulty.toInt()
+	b.BaseFeePerGas = dec.BaseFeePerGas.toInt()
+	b.Transactions = nil
+	if dec.Transactions != nil {
+		b.Transactions = make([]Hash, len(dec.Transactions))
+		for i, h := range dec.Transactions {
+			b.Transactions[i] = Hash(h)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the quantities of a block as hex strings.
+func (b Block) MarshalJSON() ([]byte, error) {
+	return marshalBlock(b, b.Transactions)
+}
+
+// UnmarshalJSON decodes a block requested with full transaction objects. The
+// transaction hashes are also set on the embedded Block.
+func (b *BlockWithTransactions) UnmarshalJSON(input []byte) error {
+	type block Block
+	dec := struct {
+		*block
+		Difficulty      *hexBig       `json:"difficulty"`
+		TotalDifficulty *hexBig       `json:"totalDifficulty"`
+		BaseFeePerGas   *hexBig       `json:"baseFeePerGas"`
+		Transactions    []Transaction `json:"transactions"`
+	}{block: (*block)(&b.Block)}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	b.Difficulty = dec.Difficulty.toInt()
+	b.TotalDifficulty = dec.TotalDifficulty.toInt()
+	b.BaseFeePerGas = dec.BaseFeePerGas.toInt()
+	b.Transactions = dec.Transactions
+	b.Block.Transactions = nil
+	if dec.Transactions != nil {
+		b.Block.Transactions = make([]Hash, len(dec.Transactions))
+		for i, tx := range dec.Transactions {
+			b.Block.Transactions[i] = tx.Hash
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the block with its full transaction objects.
+func (b BlockWithTransactions) MarshalJSON() ([]byte, error) {
+	return marshalBlock(b.Block, b.Transactions)
+}
+
+// marshalBlock encodes b with txs in place of its transactions.
+func marshalBlock(b Block, txs interface{}) ([]byte, error) {
+	type block Block
+	return json.Marshal(struct {
+		block
+		Difficulty      *hexBig     `json:"difficulty,omitempty"`
+		TotalDifficulty *hexBig     `json:"totalDifficulty,omitempty"`
+		BaseFeePerGas   *hexBig     `json:"baseFeePerGas,omitempty"`
+		Transactions    interface{} `json:"transactions"`
+	}{
+		block:           block(b),
+		Difficulty:      (*hexBig)(b.Difficulty),
+		TotalDifficulty: (*hexBig)(b.TotalDifficulty),
+		BaseFeePerGas:   (*hexBig)(b.BaseFeePerGas),
+		Transactions:    txs,
+	})
+}
+
+// UnmarshalJSON decodes the hex-encoded quantities of an uncle block.
+func (b *UncleBlock) UnmarshalJSON(input []byte) error {
+	type uncleBlock UncleBlock
+	dec := struct {
+		*uncleBlock
+		Difficulty      *hexBig `json:"difficulty"`
+		TotalDifficulty *hexBig `json:"totalDifficulty"`
+	}{uncleBlock: (*uncleBlock)(b)}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	b.Difficulty = dec.Difficulty.toInt()
+	b.TotalDifficulty = dec.TotalDifficulty.toInt()
+	return nil
+}
+
+// MarshalJSON encodes the quantities of an uncle block as hex strings.
+func (b UncleBlock) MarshalJSON() ([]byte, error) {
+	type uncleBlock UncleBlock
+	return json.Marshal(struct {
+		uncleBlock
+		Difficulty      *hexBig `json:"difficulty,omitempty"`
+		TotalDifficulty *hexBig `json:"totalDifficulty,omitempty"`
+	}{
+		uncleBlock:      uncleBlock(b),
+		Difficulty:      (*hexBig)(b.Difficulty),
+		TotalDifficulty: (*hexBig)(b.TotalDifficulty),
+	})
+}
+
+// UnmarshalJSON decodes the hex-encoded quantities of a transaction.
+func (tx *Transaction) UnmarshalJSON(input []byte) error {
+	type transaction Transaction
+	dec := struct {
+		*transaction
+		Value                *hexBig `json:"value"`
+		GasPrice             *hexBig `json:"gasPrice"`
+		ChainID              *hexBig `json:"chainId"`
+		MaxFeePerGas         *hexBig `json:"maxFeePerGas"`
+		MaxPriorityFeePerGas *hexBig `json:"maxPriorityFeePerGas"`
+		MaxFeePerBlobGas     *hexBig `json:"maxFeePerBlobGas"`
+		V                    *hexBig `json:"v"`
+		R                    *hexBig `json:"r"`
+		S                    *hexBig `json:"s"`
+	}{transaction: (*transaction)(tx)}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	tx.Value = dec.Value.toInt()
+	tx.GasPrice = dec.GasPrice.toInt()
+	tx.ChainID = dec.ChainID.toInt()
+	tx.MaxFeePerGas = dec.MaxFeePerGas.toInt()
+	tx.MaxPriorityFeePerGas = dec.MaxPriorityFeePerGas.toInt()
+	tx.MaxFeePerBlobGas = dec.MaxFeePerBlobGas.toInt()
+	tx.V = dec.V.toInt()
+	tx.R = dec.R.toInt()
+	tx.S = dec.S.toInt()
+	return nil
+}
+
+// MarshalJSON encodes the quantities of a transaction as hex strings.
+func (tx Transaction) MarshalJSON() ([]byte, error) {
+	type transaction Transaction
+	return json.Marshal(struct {
+		transaction
+		Value                *hexBig `json:"value,omitempty"`
+		GasPrice             *hexBig `json:"gasPrice,omitempty"`
+		ChainID              *hexBig `json:"chainId,omitempty"`
+		MaxFeePerGas         *hexBig `json:"maxFeePerGas,omitempty"`
+		MaxPriorityFeePerGas *hexBig `json:"maxPriorityFeePerGas,omitempty"`
+		MaxFeePerBlobGas     *hexBig `json:"maxFeePerBlobGas,omitempty"`
+		V                    *hexBig `json:"v,omitempty"`
+		R                    *hexBig `json:"r,omitempty"`
+		S                    *hexBig `json:"s,omitempty"`
+	}{
+		transaction:          transaction(tx),
+		Value:                (*hexBig)(tx.Value),
+		GasPrice:             (*hexBig)(tx.GasPrice),
+		ChainID:              (*hexBig)(tx.ChainID),
+		MaxFeePerGas:         (*hexBig)(tx.MaxFeePerGas),
+		MaxPriorityFeePerGas: (*hexBig)(tx.MaxPriorityFeePerGas),
+		MaxFeePerBlobGas:     (*hexBig)(tx.MaxFeePerBlobGas),
+		V:                    (*hexBig)(tx.V),
+		R:                    (*hexBig)(tx.R),
+		S:                    (*hexBig)(tx.S),
+	})
+}
+
+// UnmarshalJSON decodes the hex-encoded quantities of an authorization.
+func (a *SetCodeAuthorization) UnmarshalJSON(input []byte) error {
+	dec := struct {
+		ChainID *hexBig  `json:"chainId"`
+		Address Address  `json:"address"`
+		Nonce   Quantity `json:"nonce"`
+		YParity Quantity `json:"yParity"`
+		R       *hexBig  `json:"r"`
+		S       *hexBig  `json:"s"`
+	}{}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	*a = SetCodeAuthorization{
+		ChainID: dec.ChainID.toInt(),
+		Address: dec.Address,
+		Nonce:   dec.Nonce,
+		YParity: dec.YParity,
+		R:       dec.R.toInt(),
+		S:       dec.S.toInt(),
+	}
+	return nil
+}
+
+// MarshalJSON encodes the quantities of an authorization as hex strings.
+func (a SetCodeAuthorization) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ChainID *hexBig  `json:"chainId"`
+		Address Address  `json:"address"`
+		Nonce   Quantity `json:"nonce"`
+		YParity Quantity `json:"yParity"`
+		R       *hexBig  `json:"r"`
+		S       *hexBig  `json:"s"`
+	}{
+		ChainID: (*hexBig)(a.ChainID),
+		Address: a.Address,
+		Nonce:   a.Nonce,
+		YParity: a.YParity,
+		R:       (*hexBig)(a.R),
+		S:       (*hexBig)(a.S),
+	})
+}
+
+// UnmarshalJSON decodes the hex-encoded quantities of a receipt.
+func (r *TransactionReceipt) UnmarshalJSON(input []byte) error {
+	type transactionReceipt TransactionReceipt
+	dec := struct {
+		*transactionReceipt
+		EffectiveGasPrice *hexBig `json:"effectiveGasPrice"`
+		BlobGasPrice      *hexBig `json:"blobGasPrice"`
+	}{transactionReceipt: (*transactionReceipt)(r)}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	r.EffectiveGasPrice = dec.EffectiveGasPrice.toInt()
+	r.BlobGasPrice = dec.BlobGasPrice.toInt()
+	return nil
+}
+
+// MarshalJSON encodes the quantities of a receipt as hex strings.
+func (r TransactionReceipt) MarshalJSON() ([]byte, error) {
+	type transactionReceipt TransactionReceipt
+	return json.Marshal(struct {
+		transactionReceipt
+		EffectiveGasPrice *hexBig `json:"effectiveGasPrice,omitempty"`
+		BlobGasPrice      *hexBig `json:"blobGasPrice,omitempty"`
+	}{
+		transactionReceipt: transactionReceipt(r),
+		EffectiveGasPrice:  (*hexBig)(r.EffectiveGasPrice),
+		BlobGasPrice:       (*hexBig)(r.BlobGasPrice),
+	})
+}
+
+// UnmarshalJSON decodes the hex-encoded quantities of a header.
+func (h *Header) UnmarshalJSON(input []byte) error {
+	type header Header
+	dec := struct {
+		*header
+		Difficulty    *hexBig `json:"difficulty"`
+		BaseFeePerGas *hexBig `json:"baseFeePerGas"`
+	}{header: (*header)(h)}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	h.Difficulty = dec.Difficulty.toInt()
+	h.BaseFeePerGas = dec.BaseFeePerGas.toInt()
+	return nil
+}
+
+// MarshalJSON encodes the quantities of a header as hex strings.
+func (h Header) MarshalJSON() ([]byte, error) {
+	type header Header
+	return json.Marshal(struct {
+		header
+		Difficulty    *hexBig `json:"difficulty,omitempty"`
+		BaseFeePerGas *hexBig `json:"baseFeePerGas,omitempty"`
+	}{
+		header:        header(h),
+		Difficulty:    (*hexBig)(h.Difficulty),
+		BaseFeePerGas: (*hexBig)(h.BaseFeePerGas),
+	})
+}
+
+// MarshalJSON encodes the quantities of a call as hex strings.
+func (c TransactionCall) MarshalJSON() ([]byte, error) {
+	type transactionCall TransactionCall
+	return json.Marshal(struct {
+		transactionCall
+		GasPrice *hexBig `json:"gasPrice,omitempty"`
+		Value    *hexBig `json:"value,omitempty"`
+	}{
+		transactionCall: transactionCall(c),
+		GasPrice:        (*hexBig)(c.GasPrice),
+		Value:           (*hexBig)(c.Value),
+	})
+}
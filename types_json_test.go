@@ -0,0 +1,237 @@
+package ginfura
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	input, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input
+}
+
+func mustHash(t *testing.T, s string) Hash {
+	t.Helper()
+
+	h, err := HexToHash(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestDecodePreLondonBlock(t *testing.T) {
+	var b Block
+	if err := json.Unmarshal(readFixture(t, "block_pre_london.json"), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Number != 46147 || b.Hash != mustHash(t, "0x4e3a3754410177e6937ef1f84bba68ea139e8d1a2258c5f85db9f1cd715a1bdd") {
+		t.Fatalf("block %d %s", b.Number, b.Hash)
+	}
+	if b.Difficulty == nil || b.Difficulty.Cmp(big.NewInt(0x1ba8f8fa5c5)) != 0 {
+		t.Fatalf("difficulty = %v", b.Difficulty)
+	}
+	if b.BaseFeePerGas != nil || b.Withdrawals != nil || b.WithdrawalsRoot != nil || b.BlobGasUsed != nil {
+		t.Fatalf("pre-London block has post-London fields: %+v", b)
+	}
+	if len(b.Transactions) != 1 || b.Transactions[0] != mustHash(t, "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060") {
+		t.Fatalf("transactions = %v", b.Transactions)
+	}
+}
+
+func TestDecodeBlockHashes(t *testing.T) {
+	var b Block
+	if err := json.Unmarshal(readFixture(t, "block_hashes.json"), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.BaseFeePerGas == nil || b.BaseFeePerGas.Int64() != 1000000000 {
+		t.Fatalf("base fee = %v", b.BaseFeePerGas)
+	}
+	if b.Difficulty == nil || b.Difficulty.Sign() != 0 {
+		t.Fatalf("difficulty = %v", b.Difficulty)
+	}
+	if len(b.Transactions) != 2 || len(b.Withdrawals) != 2 || b.Withdrawals[1].Amount != 0x10b86b2 {
+		t.Fatalf("transactions = %v, withdrawals = %+v", b.Transactions, b.Withdrawals)
+	}
+	if b.BlobGasUsed == nil || *b.BlobGasUsed != 0x40000 || b.ExcessBlobGas == nil || b.ParentBeaconBlockRoot == nil {
+		t.Fatalf("missing Cancun fields: %+v", b)
+	}
+}
+
+func TestDecodeBlockWithTransactions(t *testing.T) {
+	input := readFixture(t, "block_full.json")
+	var b BlockWithTransactions
+	if err := json.Unmarshal(input, &b); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Transactions) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(b.Transactions))
+	}
+
+	dynamic, blob := b.Transactions[0], b.Transactions[1]
+	if dynamic.Type != DynamicFeeTxType || blob.Type != BlobTxType {
+		t.Fatalf("types = %v, %v", dynamic.Type, blob.Type)
+	}
+	if dynamic.MaxFeePerGas.Int64() != 30000000000 || dynamic.MaxPriorityFeePerGas.Int64() != 1000000000 {
+		t.Fatalf("fees = %v, %v", dynamic.MaxFeePerGas, dynamic.MaxPriorityFeePerGas)
+	}
+	if dynamic.Value.String() != "1000000000000000000" || len(dynamic.AccessList) != 1 || len(dynamic.AccessList[0].StorageKeys) != 1 {
+		t.Fatalf("value = %v, access list = %+v", dynamic.Value, dynamic.AccessList)
+	}
+	if blob.MaxFeePerBlobGas == nil || len(blob.BlobVersionedHashes) != 2 || blob.BlobVersionedHashes[0][0] != 0x01 {
+		t.Fatalf("blob fields = %v, %v", blob.MaxFeePerBlobGas, blob.BlobVersionedHashes)
+	}
+	if blob.YParity == nil || *blob.YParity != 0 || blob.V.Sign() != 0 {
+		t.Fatalf("signature = %v, %v", blob.YParity, blob.V)
+	}
+
+	// the embedded block lists the transaction hashes, as when decoded as a
+	// Block.
+	var hashes Block
+	if err := json.Unmarshal(input, &hashes); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b.Block, hashes) {
+		t.Fatalf("embedded block = %+v, want %+v", b.Block, hashes)
+	}
+	if b.Block.Transactions[1] != blob.Hash {
+		t.Fatalf("transaction hash = %s, want %s", b.Block.Transactions[1], blob.Hash)
+	}
+}
+
+func TestDecodePendingBlock(t *testing.T) {
+	var b Block
+	if err := json.Unmarshal(readFixture(t, "block_pending.json"), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Hash != (Hash{}) || b.Miner != (Address{}) || b.Nonce != nil || b.TotalDifficulty != nil {
+		t.Fatalf("pending block = %+v", b)
+	}
+	if b.Transactions == nil || len(b.Transactions) != 0 {
+		t.Fatalf("transactions = %#v, want empty", b.Transactions)
+	}
+}
+
+// roundTrip decodes input into v, then encodes and decodes it again into
+// decoded. It returns both encodings.
+func roundTrip(t *testing.T, input []byte, v, decoded interface{}) ([]byte, []byte) {
+	t.Helper()
+
+	if err := json.Unmarshal(input, v); err != nil {
+		t.Fatal(err)
+	}
+	first, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(first, decoded); err != nil {
+		t.Fatal(err)
+	}
+	second, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return first, second
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		full bool // transactions are objects
+		// exact reports whether decoding the encoding gives the same value.
+		// Empty access lists and null byte strings are encoded as absent
+		// fields and 0x respectively.
+		exact bool
+	}{
+		{"block_pre_london.json", false, true},
+		{"block_hashes.json", false, true},
+		{"block_full.json", true, false},
+		{"block_pending.json", true, false},
+	}
+
+	for _, test := range tests {
+		input := readFixture(t, test.name)
+
+		var b, decoded Block
+		first, second := roundTrip(t, input, &b, &decoded)
+		if string(first) != string(second) {
+			t.Errorf("%s: Block encodings differ\n%s\n%s", test.name, first, second)
+		}
+		if test.exact && !reflect.DeepEqual(b, decoded) {
+			t.Errorf("%s: round trip of Block\n got %+v\nwant %+v", test.name, decoded, b)
+		}
+
+		if !test.full {
+			continue
+		}
+		var full, decodedFull BlockWithTransactions
+		first, second = roundTrip(t, input, &full, &decodedFull)
+		if string(first) != string(second) {
+			t.Errorf("%s: BlockWithTransactions encodings differ\n%s\n%s", test.name, first, second)
+		}
+		if len(decodedFull.Transactions) != len(full.Transactions) || !reflect.DeepEqual(decodedFull.Block.Transactions, full.Block.Transactions) {
+			t.Errorf("%s: transactions were not preserved", test.name)
+		}
+		for i, tx := range decodedFull.Transactions {
+			if tx.Type != full.Transactions[i].Type || tx.MaxFeePerGas.Cmp(full.Transactions[i].MaxFeePerGas) != 0 {
+				t.Errorf("%s: transaction #%d = %+v, want %+v", test.name, i, tx, full.Transactions[i])
+			}
+		}
+	}
+}
+
+func TestDecodePragueHeader(t *testing.T) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(readFixture(t, "block_hashes.json"), &fields); err != nil {
+		t.Fatal(err)
+	}
+	requestsHash := "0xe3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	fields["requestsHash"] = requestsHash
+	input, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b Block
+	if err := json.Unmarshal(input, &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.RequestsHash == nil || *b.RequestsHash != mustHash(t, requestsHash) {
+		t.Fatalf("requests hash = %v", b.RequestsHash)
+	}
+	if h := b.Header(); h.RequestsHash == nil || *h.RequestsHash != *b.RequestsHash {
+		t.Fatalf("header requests hash = %v", h.RequestsHash)
+	}
+
+	var h Header
+	if err := json.Unmarshal(input, &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.RequestsHash == nil || *h.RequestsHash != *b.RequestsHash {
+		t.Fatalf("decoded header requests hash = %v", h.RequestsHash)
+	}
+	if !reflect.DeepEqual(h, b.Header()) {
+		t.Fatalf("decoded header = %+v, want %+v", h, b.Header())
+	}
+
+	// blocks before Prague have no requests hash.
+	var pre Block
+	if err := json.Unmarshal(readFixture(t, "block_hashes.json"), &pre); err != nil {
+		t.Fatal(err)
+	}
+	if pre.RequestsHash != nil {
+		t.Fatalf("requests hash = %v, want nil", pre.RequestsHash)
+	}
+}
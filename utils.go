@@ -1,5 +1,12 @@
 package ginfura
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
 const (
 	AddressLength = 20
 )
@@ -35,3 +42,29 @@ func isHexAddress(s string) bool {
 	}
 	return len(s) == 2*AddressLength && isHex(s)
 }
+
+// isNull reports whether result is missing or the JSON null.
+func isNull(result json.RawMessage) bool {
+	return len(result) == 0 || bytes.Equal(result, []byte("null"))
+}
+
+// parseBig parses a hex-encoded quantity with 0x prefix, or a decimal one.
+func parseBig(str string) (*big.Int, error) {
+	base := 10
+	digits := str
+	if hasHexPrefix(str) {
+		base = 16
+		digits = str[2:]
+	}
+
+	v, ok := new(big.Int).SetString(digits, base)
+	if !ok || digits == "" {
+		return nil, fmt.Errorf("invalid quantity %q", str)
+	}
+	return v, nil
+}
+
+// encodeBig returns the hex encoding of v with 0x prefix.
+func encodeBig(v *big.Int) string {
+	return "0x" + v.Text(16)
+}
@@ -1,355 +1,357 @@
 package ginfura
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-func (g *Ginfura) SubscribePendingTransaction() (<-chan string, chan struct{}, error) {
+// unsubscribeTimeout bounds how long an unsubscribe waits for the server
+// acknowledgement before the connection is closed anyway.
+const unsubscribeTimeout = 5 * time.Second
 
-	// initialize subscription struct for pending transaction event.
-	sub := &subscription{}
-
-	// open websocket connection
-	c, _, err := websocket.DefaultDialer.Dial(g.wsURL, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	sub.conn = c
-
-	// prepare message to send to infura server
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_subscribe",
-		"params":  []interface{}{"newPendingTransactions"},
-		"id":      1,
-	}
-	jsonValue, err := json.Marshal(values)
+// SubscribePendingTransaction subscribes to the hashes of new pending
+// transactions. The subscription ends when ctx is cancelled or the returned
+// done channel is closed, after which the queue is closed.
+func (g *Ginfura) SubscribePendingTransaction(ctx context.Context) (<-chan Hash, chan struct{}, error) {
+	sub, err := g.subscribe(ctx, NewPendingTransaction, "newPendingTransactions")
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// send request to infura server
-	err = c.WriteMessage(websocket.TextMessage, jsonValue)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	for {
-		// Read message from infura server.
-		_, message, err := c.ReadMessage()
-		if err != nil {
-			return nil, nil, err
-		}
-
-		subResp := subscriptionResp{}
-		json.Unmarshal(message, &subResp)
-		sub.subscriptionID = subResp.Result
-
-		g.subscriptionMap.Set(NewPendingTransaction, sub)
-		break
-	}
-
 	done := make(chan struct{})
-	pendingTxQueue := make(chan string)
-	go listenForPendingTx(g, pendingTxQueue, done)
+	pendingTxQueue := make(chan Hash)
+	go g.watchSubscription(ctx, NewPendingTransaction, sub, done)
+	go func() {
+		defer close(pendingTxQueue)
+		g.listen(NewPendingTransaction, sub, func(result json.RawMessage) bool {
+			var txHash Hash
+			if err := json.Unmarshal(result, &txHash); err != nil {
+				return false
+			}
+			select {
+			case pendingTxQueue <- txHash:
+				return true
+			case <-sub.stopping:
+				return false
+			}
+		})
+	}()
 
 	return pendingTxQueue, done, nil
 }
 
-func (g *Ginfura) UnSubscribePendingTransaction() {
-
-	pendingTxSub := &subscription{}
-	if tmp, ok := g.subscriptionMap.Get(NewPendingTransaction); ok {
-		pendingTxSub = tmp.(*subscription)
-	}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_unsubscribe",
-		"params":  []interface{}{pendingTxSub.subscriptionID},
-		"id":      1,
-	}
-	jsonValue, err := json.Marshal(values)
-	if err != nil {
-		return
-	}
-	err = pendingTxSub.conn.WriteMessage(websocket.TextMessage, jsonValue)
-	if err != nil {
-		return
-	}
-
-	resp := unsubscribeResp{}
-	for {
-		_, message, err := pendingTxSub.conn.ReadMessage()
-		if err != nil {
-			return
-		}
-		json.Unmarshal(message, &resp)
-
-		if resp.Result == true {
-			pendingTxSub.conn.Close()
-			g.subscriptionMap.Remove(NewPendingTransaction)
-		}
-		return
-	}
+// UnSubscribePendingTransaction cancels the pending transaction subscription.
+func (g *Ginfura) UnSubscribePendingTransaction(ctx context.Context) error {
+	return g.unsubscribe(ctx, NewPendingTransaction)
 }
 
-func listenForPendingTx(g *Ginfura, pendingTxQueue chan<- string, done chan struct{}) {
-	pendingTxSub := &subscription{}
-	if tmp, ok := g.subscriptionMap.Get(NewPendingTransaction); ok {
-		pendingTxSub = tmp.(*subscription)
+// SubscribeNewHead subscribes to the headers of newly imported blocks. The
+// subscription ends when ctx is cancelled or the returned done channel is
+// closed, after which the queue is closed.
+func (g *Ginfura) SubscribeNewHead(ctx context.Context) (<-chan Header, chan struct{}, error) {
+	sub, err := g.subscribe(ctx, NewHead, "newHeads")
+	if err != nil {
+		return nil, nil, err
 	}
 
-	resp := txPendingResp{}
-	for {
-		select {
-		case <-done:
-			g.UnSubscribePendingTransaction()
-			return
-		default:
-			_, message, err := pendingTxSub.conn.ReadMessage()
-			if err != nil {
-				return
+	done := make(chan struct{})
+	newHeadQueue := make(chan Header)
+	go g.watchSubscription(ctx, NewHead, sub, done)
+	go func() {
+		defer close(newHeadQueue)
+		g.listen(NewHead, sub, func(result json.RawMessage) bool {
+			head := Header{}
+			if err := json.Unmarshal(result, &head); err != nil {
+				return false
 			}
+			select {
+			case newHeadQueue <- head:
+				return true
+			case <-sub.stopping:
+				return false
+			}
+		})
+	}()
 
-			json.Unmarshal(message, &resp)
-
-			pendingTxQueue <- resp.Params.Result
-		}
-	}
+	return newHeadQueue, done, nil
 }
 
-func (g *Ginfura) SubscribeNewHead() (<-chan newHeadResult, chan struct{}, error) {
-
-	// initialize subscription struct for pending transaction event.
-	sub := &subscription{}
-
-	// open websocket connection
-	c, _, err := websocket.DefaultDialer.Dial(g.wsURL, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	sub.conn = c
-
-	// prepare message to send to infura server
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_subscribe",
-		"params":  []interface{}{"newHeads"},
-		"id":      1,
-	}
-	jsonValue, err := json.Marshal(values)
-	if err != nil {
-		return nil, nil, err
-	}
+// UnSubscribeNewHead cancels the new heads subscription.
+func (g *Ginfura) UnSubscribeNewHead(ctx context.Context) error {
+	return g.unsubscribe(ctx, NewHead)
+}
 
-	// send request to infura server
-	err = c.WriteMessage(websocket.TextMessage, jsonValue)
+// SubscribeNewLog subscribes to logs matching params. The subscription ends
+// when ctx is cancelled or the returned done channel is closed, after which
+// the queue is closed.
+func (g *Ginfura) SubscribeNewLog(ctx context.Context, params *LogRequestParams) (<-chan Log, chan struct{}, error) {
+	sub, err := g.subscribe(ctx, NewLog, "logs", params)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	for {
-		// Read message from infura server.
-		_, message, err := c.ReadMessage()
-		if err != nil {
-			return nil, nil, err
-		}
-
-		subResp := subscriptionResp{}
-		json.Unmarshal(message, &subResp)
-		sub.subscriptionID = subResp.Result
-
-		g.subscriptionMap.Set(NewHead, sub)
-		break
-	}
-
 	done := make(chan struct{})
-	newHeadQueue := make(chan newHeadResult)
-	go listenForNewHead(g, newHeadQueue, done)
+	logQueue := make(chan Log)
+	go g.watchSubscription(ctx, NewLog, sub, done)
+	go func() {
+		defer close(logQueue)
+		g.listen(NewLog, sub, func(result json.RawMessage) bool {
+			log := Log{}
+			if err := json.Unmarshal(result, &log); err != nil {
+				return false
+			}
+			select {
+			case logQueue <- log:
+				return true
+			case <-sub.stopping:
+				return false
+			}
+		})
+	}()
 
-	return newHeadQueue, done, nil
+	return logQueue, done, nil
 }
 
-func (g *Ginfura) UnSubscribeNewHead() {
+// UnSubscribeNewLog cancels the logs subscription.
+func (g *Ginfura) UnSubscribeNewLog(ctx context.Context) error {
+	return g.unsubscribe(ctx, NewLog)
+}
 
-	newHeadSub := &subscription{}
-	if tmp, ok := g.subscriptionMap.Get(NewHead); ok {
-		newHeadSub = tmp.(*subscription)
+// subscribe opens a websocket connection and registers an eth_subscribe
+// subscription with the given params under subType.
+func (g *Ginfura) subscribe(ctx context.Context, subType string, params ...interface{}) (*subscription, error) {
+	if g.subscriptionMap.Has(subType) {
+		return nil, errAlreadySubscribe
 	}
 
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_unsubscribe",
-		"params":  []interface{}{newHeadSub.subscriptionID},
-		"id":      1,
-	}
-	jsonValue, err := json.Marshal(values)
-	if err != nil {
-		return
-	}
-	err = newHeadSub.conn.WriteMessage(websocket.TextMessage, jsonValue)
+	// open websocket connection
+	wsURL, header := g.websocketEndpoint()
+	c, _, err := g.wsDialer.DialContext(ctx, wsURL, header)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	resp := unsubscribeResp{}
-	for {
-		_, message, err := newHeadSub.conn.ReadMessage()
-		if err != nil {
-			return
+	// abort the handshake if ctx is cancelled while waiting for infura.
+	handshakeDone := make(chan struct{})
+	defer close(handshakeDone)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close()
+		case <-handshakeDone:
 		}
-		json.Unmarshal(message, &resp)
-
-		if resp.Result == true {
-			newHeadSub.conn.Close()
-			g.subscriptionMap.Remove(NewHead)
+	}()
+
+	sub := newSubscription(c)
+	msg := g.newMessage("eth_subscribe", params...)
+	call := newRPCCall(TransportWebsocket, msg)
+	err = g.intercept(ctx, call, func(ctx context.Context, call *RPCCall) error {
+		if err := g.limiter.wait(ctx, msg.Method); err != nil {
+			return err
+		}
+		if err := sub.write(msg); err != nil {
+			return contextError(ctx, err)
 		}
-		return
-	}
-}
-
-func listenForNewHead(g *Ginfura, newHeadQueue chan<- newHeadResult, done chan struct{}) {
-	newHeadSub := &subscription{}
-	if tmp, ok := g.subscriptionMap.Get(NewHead); ok {
-		newHeadSub = tmp.(*subscription)
-	}
 
-	resp := newHeadsResp{}
-	for {
-		select {
-		case <-done:
-			g.UnSubscribeNewHead()
-			return
-		default:
-			_, message, err := newHeadSub.conn.ReadMessage()
+		for {
+			// Read message from infura server.
+			_, message, err := c.ReadMessage()
 			if err != nil {
-				return
+				return contextError(ctx, err)
 			}
 
-			json.Unmarshal(message, &resp)
-
-			newHeadQueue <- resp.Params.Result
+			resp := wsMessage{}
+			if err := json.Unmarshal(message, &resp); err != nil {
+				return err
+			}
+			if resp.ID != msg.ID {
+				continue
+			}
+			call.Response = message
+			if resp.Error != nil {
+				return resp.Error
+			}
+			return json.Unmarshal(resp.Result, &sub.subscriptionID)
 		}
-	}
-}
-
-func (g *Ginfura) SubscribeNewLog(params *LogRequestParams) (<-chan logsResult, chan struct{}, error) {
-
-	// initialize subscription struct for pending transaction event.
-	sub := &subscription{}
-
-	// open websocket connection
-	c, _, err := websocket.DefaultDialer.Dial(g.wsURL, nil)
+	})
 	if err != nil {
-		return nil, nil, err
-	}
-
-	sub.conn = c
-
-	// prepare message to send to infura server
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_subscribe",
-		"params":  []interface{}{"logs", params},
-		"id":      1,
-	}
-	jsonValue, err := json.Marshal(values)
-	if err != nil {
-		return nil, nil, err
+		c.Close()
+		return nil, err
 	}
 
-	// send request to infura server
-	err = c.WriteMessage(websocket.TextMessage, jsonValue)
-	if err != nil {
-		return nil, nil, err
+	if !g.subscriptionMap.SetIfAbsent(subType, sub) {
+		c.Close()
+		return nil, errAlreadySubscribe
 	}
+	return sub, nil
+}
 
-	for {
-		// Read message from infura server.
-		_, message, err := c.ReadMessage()
-		if err != nil {
-			return nil, nil, err
+// unsubscribe cancels the subscription registered under subType and closes
+// its connection. The eth_unsubscribe request bypasses the rate limiter, so
+// that exhausted credits cannot keep the connection open, and the
+// acknowledgement is awaited for at most unsubscribeTimeout.
+func (g *Ginfura) unsubscribe(ctx context.Context, subType string) error {
+	tmp, ok := g.subscriptionMap.Get(subType)
+	if !ok {
+		return errNotSubscribed(subType)
+	}
+	sub := tmp.(*subscription)
+	defer g.closeSubscription(subType, sub)
+
+	// stop delivering notifications so the listener keeps reading and can
+	// pick up the acknowledgement.
+	sub.stop()
+
+	ctx, cancel := context.WithTimeout(ctx, unsubscribeTimeout)
+	defer cancel()
+
+	msg := g.newMessage("eth_unsubscribe", sub.subscriptionID)
+	call := newRPCCall(TransportWebsocket, msg)
+	return g.intercept(ctx, call, func(ctx context.Context, call *RPCCall) error {
+		if err := sub.write(msg); err != nil {
+			return err
 		}
 
-		subResp := subscriptionResp{}
-		json.Unmarshal(message, &subResp)
-		sub.subscriptionID = subResp.Result
+		for {
+			select {
+			case resp := <-sub.responses:
+				if resp.ID != msg.ID {
+					continue
+				}
+				call.Response = resp.raw
+				if resp.Error != nil {
+					return resp.Error
+				}
+				return nil
+			case <-sub.closed:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
 
-		g.subscriptionMap.Set(NewLog, sub)
-		break
+// websocketEndpoint returns the websocket URL and handshake headers to use,
+// preferring the healthiest failover endpoint that has a websocket URL.
+func (g *Ginfura) websocketEndpoint() (string, http.Header) {
+	if g.failover == nil {
+		return g.wsURL, g.header
 	}
 
-	done := make(chan struct{})
-	logQueue := make(chan logsResult)
-	go listenForNewLog(g, logQueue, done)
-
-	return logQueue, done, nil
+	for _, endpoint := range g.failover.candidates() {
+		if endpoint.WSURL == "" {
+			continue
+		}
+		header := g.header.Clone()
+		for key, values := range endpoint.Header {
+			header[key] = values
+		}
+		return endpoint.WSURL, header
+	}
+	return g.wsURL, g.header
 }
 
-func (g *Ginfura) UnSubscribeNewLog() {
+// websocketDialer returns a dialer using the proxy, TLS config and dial
+// function of the transport of client, if it is an *http.Transport, with the
+// handshake bound by timeout.
+func websocketDialer(client *http.Client, timeout time.Duration) *websocket.Dialer {
+	dialer := *websocket.DefaultDialer
 
-	newLogSub := &subscription{}
-	if tmp, ok := g.subscriptionMap.Get(NewLog); ok {
-		newLogSub = tmp.(*subscription)
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
 	}
-
-	values := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "eth_unsubscribe",
-		"params":  []interface{}{newLogSub.subscriptionID},
-		"id":      1,
+	if t, ok := transport.(*http.Transport); ok {
+		dialer.Proxy = t.Proxy
+		dialer.TLSClientConfig = t.TLSClientConfig
+		dialer.NetDialContext = t.DialContext
 	}
-	jsonValue, err := json.Marshal(values)
-	if err != nil {
-		return
+	if timeout > 0 {
+		dialer.HandshakeTimeout = timeout
 	}
-	err = newLogSub.conn.WriteMessage(websocket.TextMessage, jsonValue)
-	if err != nil {
+	return &dialer
+}
+
+// watchSubscription unsubscribes once ctx is cancelled or done is closed.
+func (g *Ginfura) watchSubscription(ctx context.Context, subType string, sub *subscription, done chan struct{}) {
+	select {
+	case <-ctx.Done():
+	case <-done:
+	case <-sub.closed:
 		return
 	}
+	g.unsubscribe(context.Background(), subType)
+}
+
+// listen reads messages from the subscription connection and passes the
+// result of each notification to handle until the connection is closed.
+// handle reports whether the notification was delivered to the subscriber.
+func (g *Ginfura) listen(subType string, sub *subscription, handle func(result json.RawMessage) bool) {
+	defer g.closeSubscription(subType, sub)
 
-	resp := unsubscribeResp{}
 	for {
-		_, message, err := newLogSub.conn.ReadMessage()
+		_, message, err := sub.conn.ReadMessage()
 		if err != nil {
 			return
 		}
-		json.Unmarshal(message, &resp)
 
-		if resp.Result == true {
-			newLogSub.conn.Close()
-			g.subscriptionMap.Remove(NewLog)
+		msg := wsMessage{raw: message}
+		if err := json.Unmarshal(message, &msg); err != nil {
+			continue
 		}
-		return
-	}
-}
 
-func listenForNewLog(g *Ginfura, logQueue chan<- logsResult, done chan struct{}) {
-	newLogSub := &subscription{}
-	if tmp, ok := g.subscriptionMap.Get(NewLog); ok {
-		newLogSub = tmp.(*subscription)
+		// responses to requests sent on this connection, e.g. eth_unsubscribe.
+		if msg.Method == "" {
+			select {
+			case sub.responses <- &msg:
+			default:
+			}
+			continue
+		}
+
+		if msg.Params.Subscription != sub.subscriptionID {
+			continue
+		}
+		g.observeNotification(subType, handle(msg.Params.Result))
 	}
+}
 
-	resp := logsResp{}
-	for {
-		select {
-		case <-done:
-			g.UnSubscribeNewLog()
-			return
-		default:
-			_, message, err := newLogSub.conn.ReadMessage()
-			if err != nil {
-				return
-			}
+// closeSubscription closes the connection of sub and removes it from the
+// subscription map.
+func (g *Ginfura) closeSubscription(subType string, sub *subscription) {
+	sub.closeOnce.Do(func() {
+		sub.stop()
+		sub.conn.Close()
+		close(sub.closed)
+		g.subscriptionMap.RemoveCb(subType, func(key string, v interface{}, exists bool) bool {
+			return exists && v == sub
+		})
+	})
+}
 
-			json.Unmarshal(message, &resp)
+// errNotSubscribed returns the error reported when subType is not subscribed.
+func errNotSubscribed(subType string) error {
+	switch subType {
+	case NewPendingTransaction:
+		return errNotSubscribePendingTransaction
+	case NewHead:
+		return errNotSubscribeNewHeads
+	case NewLog:
+		return errNotSubscribeLogs
+	}
+	return errNotOpenWebsocketConnection
+}
 
-			logQueue <- resp.Params.Result
-		}
+// contextError prefers the context error over err once ctx is done, since
+// closing the connection on cancellation surfaces as a network error.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
 	}
+	return err
 }
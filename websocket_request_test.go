@@ -0,0 +1,153 @@
+package ginfura
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsServer is a websocket JSON-RPC endpoint that accepts subscriptions and
+// records the methods it receives.
+type wsServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	methods []string
+	conns   int
+}
+
+func newWSServer(t *testing.T, tls bool) *wsServer {
+	t.Helper()
+
+	s := &wsServer{}
+	upgrader := websocket.Upgrader{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.conns--
+			s.mu.Unlock()
+		}()
+
+		for {
+			var req jsonrpcMessage
+			if err := c.ReadJSON(&req); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.methods = append(s.methods, req.Method)
+			s.mu.Unlock()
+
+			var result interface{} = "0xcd0c3e8af590364c09d0fa6a1210faf5"
+			if req.Method == "eth_unsubscribe" {
+				result = true
+			}
+			c.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		}
+	})
+
+	if tls {
+		s.Server = httptest.NewTLSServer(handler)
+	} else {
+		s.Server = httptest.NewServer(handler)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *wsServer) wsURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+// state returns the methods received so far and the number of open
+// connections.
+func (s *wsServer) state() ([]string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.methods...), s.conns
+}
+
+func TestWebsocketUsesTransport(t *testing.T) {
+	srv := newWSServer(t, true)
+
+	// the default dialer does not trust the certificate of the test server.
+	g := NewGinfura("mainnet", "", WithWebsocketURL(srv.wsURL()))
+	if _, _, err := g.SubscribeNewHead(context.Background()); err == nil {
+		t.Fatal("expected a certificate error")
+	}
+
+	transport := srv.Client().Transport.(*http.Transport)
+	g = NewGinfura("mainnet", "", WithWebsocketURL(srv.wsURL()), WithTransport(transport))
+	_, done, err := g.SubscribeNewHead(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(done)
+}
+
+func TestWebsocketDialer(t *testing.T) {
+	srv := newWSServer(t, true)
+
+	dialer := &websocket.Dialer{
+		TLSClientConfig:  &tls.Config{RootCAs: srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs},
+		HandshakeTimeout: time.Second,
+	}
+	g := NewGinfura("mainnet", "", WithWebsocketURL(srv.wsURL()), WithWebsocketDialer(dialer))
+	_, done, err := g.SubscribeNewHead(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(done)
+}
+
+func TestWebsocketDialerTimeout(t *testing.T) {
+	g := NewGinfura("mainnet", "", WithRequestTimeout(3*time.Second))
+	if g.wsDialer.HandshakeTimeout != 3*time.Second {
+		t.Fatalf("handshake timeout = %v, want 3s", g.wsDialer.HandshakeTimeout)
+	}
+}
+
+func TestUnsubscribeCreditsExhausted(t *testing.T) {
+	srv := newWSServer(t, false)
+	g := NewGinfura("mainnet", "", WithWebsocketURL(srv.wsURL()), WithRateLimit(RateLimit{CreditsPerDay: 1}))
+
+	_, done, err := g.SubscribeNewHead(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	close(done)
+
+	waitFor(t, "the subscription to close", func() bool {
+		_, conns := srv.state()
+		return conns == 0 && !g.subscriptionMap.Has(NewHead)
+	})
+	if elapsed := time.Since(start); elapsed >= unsubscribeTimeout {
+		t.Fatalf("closing took %v", elapsed)
+	}
+	if methods, _ := srv.state(); len(methods) != 2 || methods[1] != "eth_unsubscribe" {
+		t.Fatalf("server received %v, want eth_subscribe and eth_unsubscribe", methods)
+	}
+
+	// new subscriptions still wait for credits.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := g.SubscribeNewHead(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}